@@ -6,7 +6,9 @@ toolchain go1.22.12
 
 require (
 	github.com/grafana/grafana-plugin-sdk-go v0.263.0
+	github.com/prometheus/client_golang v1.20.5
 	golang.org/x/text v0.21.0
+	golang.org/x/time v0.8.0
 )
 
 require (
@@ -59,7 +61,6 @@ require (
 	github.com/olekukonko/tablewriter v0.0.5 // indirect
 	github.com/perimeterx/marshmallow v1.1.5 // indirect
 	github.com/pierrec/lz4/v4 v4.1.21 // indirect
-	github.com/prometheus/client_golang v1.20.5 // indirect
 	github.com/prometheus/client_model v0.6.1 // indirect
 	github.com/prometheus/common v0.61.0 // indirect
 	github.com/prometheus/procfs v0.15.1 // indirect
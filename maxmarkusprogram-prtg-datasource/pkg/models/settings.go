@@ -2,20 +2,56 @@ package models
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 )
 
+// Supported PRTG authentication modes.
+const (
+	AuthModeApiToken = "apitoken"
+	AuthModePasshash = "passhash"
+	AuthModePassword = "password"
+)
+
 type PluginSettings struct {
-	Path      string                `json:"path"`
-	CacheTime time.Duration         `json:"cacheTime"`
-	Secrets   *SecretPluginSettings `json:"-"`
+	Path      string        `json:"path"`
+	CacheTime time.Duration `json:"cacheTime"`
+	// Timeout is the request timeout in seconds, using the same key as Grafana's HTTP settings.
+	Timeout  int64  `json:"timeout"`
+	AuthMode string `json:"authMode"`
+	Username string `json:"username"`
+	// MaxConcurrentQueries limits how many queries of one request run in parallel.
+	MaxConcurrentQueries int `json:"maxConcurrentQueries"`
+	// RetryMaxAttempts is the number of attempts per PRTG request including the first one.
+	RetryMaxAttempts int `json:"retryMaxAttempts"`
+	// RetryBaseDelay and RetryMaxDelay bound the backoff between attempts.
+	RetryBaseDelay time.Duration `json:"retryBaseDelay"`
+	RetryMaxDelay  time.Duration `json:"retryMaxDelay"`
+	// RequestsPerSecond, RequestBurst and MaxInFlightRequests limit the load on the PRTG
+	// web server. 0 uses the defaults, negative values disable the limit.
+	RequestsPerSecond   float64 `json:"requestsPerSecond"`
+	RequestBurst        int     `json:"requestBurst"`
+	MaxInFlightRequests int     `json:"maxInFlightRequests"`
+	// TLS settings use the same keys as Grafana's built-in data sources.
+	TLSSkipVerify     bool                  `json:"tlsSkipVerify"`
+	TLSAuth           bool                  `json:"tlsAuth"`
+	TLSAuthWithCACert bool                  `json:"tlsAuthWithCACert"`
+	TLSServerName     string                `json:"serverName"`
+	Secrets           *SecretPluginSettings `json:"-"`
 }
 
 type SecretPluginSettings struct {
-	ApiKey string `json:"apiKey"`
+	ApiKey        string `json:"apiKey"`
+	Passhash      string `json:"passhash"`
+	Password      string `json:"password"`
+	TLSCACert     string `json:"tlsCACert"`
+	TLSClientCert string `json:"tlsClientCert"`
+	TLSClientKey  string `json:"tlsClientKey"`
 }
 
 func LoadPluginSettings(source backend.DataSourceInstanceSettings) (*PluginSettings, error) {
@@ -24,6 +60,15 @@ func LoadPluginSettings(source backend.DataSourceInstanceSettings) (*PluginSetti
 	if err != nil {
 		return nil, fmt.Errorf("could not unmarshal PluginSettings json: %w", err)
 	}
+	// The config editor stores cacheTime in seconds.
+	settings.CacheTime *= time.Second
+	// Retry delays are stored in milliseconds.
+	settings.RetryBaseDelay *= time.Millisecond
+	settings.RetryMaxDelay *= time.Millisecond
+	// Existing data sources were configured before authMode existed and use an API token.
+	if settings.AuthMode == "" {
+		settings.AuthMode = AuthModeApiToken
+	}
 
 	settings.Secrets = loadSecretPluginSettings(source.DecryptedSecureJSONData)
 
@@ -32,6 +77,68 @@ func LoadPluginSettings(source backend.DataSourceInstanceSettings) (*PluginSetti
 
 func loadSecretPluginSettings(source map[string]string) *SecretPluginSettings {
 	return &SecretPluginSettings{
-		ApiKey: source["apiKey"],
+		ApiKey:        source["apiKey"],
+		Passhash:      source["passhash"],
+		Password:      source["password"],
+		TLSCACert:     source["tlsCACert"],
+		TLSClientCert: source["tlsClientCert"],
+		TLSClientKey:  source["tlsClientKey"],
+	}
+}
+
+// ValidateAuth checks that the credentials required by the configured auth mode are present.
+func (s *PluginSettings) ValidateAuth() error {
+	switch s.AuthMode {
+	case AuthModeApiToken:
+		if s.Secrets.ApiKey == "" {
+			return errors.New("API key is missing")
+		}
+	case AuthModePasshash:
+		if s.Username == "" {
+			return errors.New("username is missing")
+		}
+		if s.Secrets.Passhash == "" {
+			return errors.New("passhash is missing")
+		}
+	case AuthModePassword:
+		if s.Username == "" {
+			return errors.New("username is missing")
+		}
+		if s.Secrets.Password == "" {
+			return errors.New("password is missing")
+		}
+	default:
+		return fmt.Errorf("unknown auth mode %q", s.AuthMode)
+	}
+	return nil
+}
+
+// BaseURL returns the normalized PRTG server URL from Path.
+// Path may be a full URL with scheme, port and path prefix (e.g. "http://proxy:8080/prtg/")
+// or a bare host as stored by older versions of the plugin, in which case https is assumed.
+func (s *PluginSettings) BaseURL() (string, error) {
+	raw := strings.TrimSpace(s.Path)
+	if raw == "" {
+		return "", errors.New("server address is missing")
 	}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid server address %q: %w", s.Path, err)
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return "", fmt.Errorf("invalid server address %q: scheme must be http or https", s.Path)
+	}
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("invalid server address %q: host is missing", s.Path)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("invalid server address %q: query strings and fragments are not supported", s.Path)
+	}
+
+	return fmt.Sprintf("%s://%s%s", scheme, u.Host, strings.TrimRight(u.EscapedPath(), "/")), nil
 }
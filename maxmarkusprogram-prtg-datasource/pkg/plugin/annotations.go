@@ -0,0 +1,70 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// handleAnnotationsQuery turns the PRTG log messages of an object in the query time range
+// into an annotation frame with time, timeEnd, title, text and tags fields.
+func (d *Datasource) handleAnnotationsQuery(ctx context.Context, qm queryModel, query backend.DataQuery) backend.DataResponse {
+	var response backend.DataResponse
+
+	messages, err := d.api.GetMessages(ctx, qm.ObjectId, query.TimeRange.From.UnixMilli(), query.TimeRange.To.UnixMilli())
+	if err != nil {
+		backend.Logger.Error("API request failed", "error", err)
+		return apiErrorResponse("API request failed", err)
+	}
+
+	var (
+		times    []time.Time
+		timeEnds []time.Time
+		titles   []string
+		texts    []string
+		tags     []json.RawMessage
+	)
+
+	for _, m := range messages.Messages {
+		timestamp, _, err := parsePRTGDateTime(m.Datetime)
+		if err != nil {
+			backend.Logger.Warn("Date parsing failed", "datetime", m.Datetime, "error", err)
+			continue
+		}
+
+		messageTags, err := json.Marshal(annotationTags(m))
+		if err != nil {
+			return backend.ErrDataResponse(backend.StatusInternal, fmt.Sprintf("error marshaling tags: %v", err))
+		}
+
+		times = append(times, timestamp)
+		timeEnds = append(timeEnds, timestamp)
+		titles = append(titles, fmt.Sprintf("%s: %s", m.Name, m.Type))
+		texts = append(texts, cleanMessageHTML(m.Message))
+		tags = append(tags, messageTags)
+	}
+
+	response.Frames = append(response.Frames, data.NewFrame("annotations",
+		data.NewField("time", nil, times),
+		data.NewField("timeEnd", nil, timeEnds),
+		data.NewField("title", nil, titles),
+		data.NewField("text", nil, texts),
+		data.NewField("tags", nil, tags),
+	))
+	return response
+}
+
+// annotationTags returns the tags of a log message: its type, status and parent object.
+func annotationTags(m PrtgMessageListItemStruct) []string {
+	tags := make([]string, 0, 3)
+	for _, tag := range []string{m.Type, m.Status, m.Parent} {
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,69 @@
+package plugin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+// Averaging modes of a metrics query besides a fixed interval in seconds.
+const (
+	averagingAuto = "auto"
+	averagingRaw  = "raw"
+)
+
+// prtgAvgIntervals lists the averaging intervals in seconds accepted by historicdata.json,
+// in ascending order. 0 returns the raw scan results.
+var prtgAvgIntervals = []int64{0, 60, 300, 900, 1800, 3600, 7200, 14400, 86400}
+
+// averagingInterval returns the avg parameter in seconds for a metrics query.
+// Without an override the interval follows the panel resolution: the larger of Grafana's
+// interval and range/MaxDataPoints, snapped down to the nearest interval PRTG accepts so
+// zoomed-in panels keep their detail. An override may be "raw" or a fixed number of seconds.
+func averagingInterval(qm queryModel, query backend.DataQuery) (int64, error) {
+	switch mode := strings.TrimSpace(strings.ToLower(qm.Averaging)); mode {
+	case "", averagingAuto:
+		step := query.Interval
+		if query.MaxDataPoints > 0 {
+			if perPoint := query.TimeRange.Duration() / time.Duration(query.MaxDataPoints); perPoint > step {
+				step = perPoint
+			}
+		}
+		return snapAvgInterval(int64(step / time.Second)), nil
+	case averagingRaw:
+		return 0, nil
+	default:
+		seconds, err := strconv.ParseInt(mode, 10, 64)
+		if err != nil || seconds < 0 {
+			return 0, fmt.Errorf("invalid averaging %q: use auto, raw or an interval in seconds", qm.Averaging)
+		}
+		return snapAvgInterval(seconds), nil
+	}
+}
+
+// snapAvgInterval returns the largest interval accepted by PRTG that does not exceed seconds.
+func snapAvgInterval(seconds int64) int64 {
+	snapped := prtgAvgIntervals[0]
+	for _, interval := range prtgAvgIntervals {
+		if interval > seconds {
+			break
+		}
+		snapped = interval
+	}
+	return snapped
+}
+
+// formatAvgInterval formats an averaging interval in seconds for notices, e.g. "5m" or "1h".
+func formatAvgInterval(seconds int64) string {
+	switch {
+	case seconds >= 3600 && seconds%3600 == 0:
+		return fmt.Sprintf("%dh", seconds/3600)
+	case seconds >= 60 && seconds%60 == 0:
+		return fmt.Sprintf("%dm", seconds/60)
+	default:
+		return fmt.Sprintf("%ds", seconds)
+	}
+}
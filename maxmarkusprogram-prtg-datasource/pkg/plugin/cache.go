@@ -0,0 +1,131 @@
+package plugin
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultCacheMaxEntries limits the number of responses kept per datasource instance.
+	defaultCacheMaxEntries = 1000
+	// defaultCacheMaxBytes limits the total size of the cached responses per datasource instance.
+	defaultCacheMaxBytes = 64 << 20
+)
+
+// cacheEntry is a single value stored in a ttlCache.
+type cacheEntry[V any] struct {
+	key       string
+	value     V
+	size      int
+	expiresAt time.Time
+}
+
+// ttlCache is a size-bounded LRU cache whose entries expire after a fixed TTL.
+// It is safe for concurrent use.
+type ttlCache[V any] struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	maxBytes   int
+	bytes      int
+	order      *list.List
+	items      map[string]*list.Element
+}
+
+// newTTLCache creates a cache that keeps entries for ttl and evicts the least
+// recently used entries once maxEntries or maxBytes is exceeded.
+func newTTLCache[V any](ttl time.Duration, maxEntries, maxBytes int) *ttlCache[V] {
+	return &ttlCache[V]{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		order:      list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+// Get returns the cached value for key if it exists and has not expired.
+func (c *ttlCache[V]) Get(key string) (V, bool) {
+	var zero V
+	if c == nil {
+		return zero, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+	entry := elem.Value.(*cacheEntry[V])
+	if time.Now().After(entry.expiresAt) {
+		c.removeElement(elem)
+		return zero, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+// Set stores value under key. size is the approximate memory footprint of the
+// value in bytes and is used to enforce maxBytes.
+func (c *ttlCache[V]) Set(key string, value V, size int) {
+	if c == nil || c.ttl <= 0 {
+		return
+	}
+	// Values larger than the whole cache are never stored.
+	if c.maxBytes > 0 && size > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.removeElement(elem)
+	}
+
+	entry := &cacheEntry[V]{
+		key:       key,
+		value:     value,
+		size:      size,
+		expiresAt: time.Now().Add(c.ttl),
+	}
+	c.items[key] = c.order.PushFront(entry)
+	c.bytes += size
+
+	for c.order.Len() > 0 &&
+		((c.maxEntries > 0 && c.order.Len() > c.maxEntries) || (c.maxBytes > 0 && c.bytes > c.maxBytes)) {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// Len returns the number of entries currently held by the cache.
+func (c *ttlCache[V]) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Clear drops all cached entries.
+func (c *ttlCache[V]) Clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	c.items = make(map[string]*list.Element)
+	c.bytes = 0
+}
+
+// removeElement removes elem from the cache. The caller must hold c.mu.
+func (c *ttlCache[V]) removeElement(elem *list.Element) {
+	entry := c.order.Remove(elem).(*cacheEntry[V])
+	delete(c.items, entry.key)
+	c.bytes -= entry.size
+}
@@ -7,9 +7,11 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/instancemgmt"
 	"github.com/maxmarkusprogram/prtg/pkg/models"
 )
@@ -22,13 +24,23 @@ var (
 	_ backend.CallResourceHandler   = (*Datasource)(nil)
 )
 
+// defaultRequestTimeout, ayarlarda timeout tanımlı değilse PRTG istekleri için kullanılır.
+const defaultRequestTimeout = 10 * time.Second
+
+// defaultMaxConcurrentQueries, bir QueryData isteğinde aynı anda çalışan sorgu sayısının varsayılan sınırıdır.
+const defaultMaxConcurrentQueries = 5
+
 // NewDatasource, plugin ayarlarından verileri çekerek yeni bir datasource örneği oluşturur.
 func NewDatasource(ctx context.Context, settings backend.DataSourceInstanceSettings) (instancemgmt.Instance, error) {
 	config, err := models.LoadPluginSettings(settings)
 	if err != nil {
 		return nil, err
 	}
-	baseURL := fmt.Sprintf("https://%s", config.Path)
+	// Path tam bir URL (şema, port, alt yol) veya eski sürümlerdeki gibi sadece host olabilir.
+	baseURL, err := config.BaseURL()
+	if err != nil {
+		return nil, err
+	}
 
 	// Eğer cache zamanı tanımlı değilse varsayılan 30 saniye kullanılır.
 	cacheTime := config.CacheTime
@@ -36,30 +48,128 @@ func NewDatasource(ctx context.Context, settings backend.DataSourceInstanceSetti
 		cacheTime = 30 * time.Second
 	}
 
+	// TLS ayarları (CA sertifikası, istemci sertifikası, doğrulamayı atlama) yüklenir.
+	tlsConfig, err := newTLSConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS settings: %w", err)
+	}
+
+	auth := Credentials{
+		Mode:     config.AuthMode,
+		ApiKey:   config.Secrets.ApiKey,
+		Username: config.Username,
+		Passhash: config.Secrets.Passhash,
+		Password: config.Secrets.Password,
+	}
+
+	// Tüm istekler için tek bir HTTP istemcisi oluşturulur; böylece bağlantılar ve TLS oturumları
+	// yeniden kullanılır, Grafana'nın proxy ayarları ve middleware'leri uygulanır.
+	opts, err := settings.HTTPClientOptions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("http client options: %w", err)
+	}
+	if config.Timeout <= 0 {
+		opts.Timeouts.Timeout = defaultRequestTimeout
+	}
+	opts.TLS = nil
+	opts.ConfigureTLSConfig = configureTLS(tlsConfig)
+
+	client, err := httpclient.New(opts)
+	if err != nil {
+		return nil, fmt.Errorf("http client: %w", err)
+	}
+
+	api := NewApi(baseURL, auth, client, cacheTime)
+	api.SetRetryPolicy(RetryPolicy{
+		MaxAttempts: config.RetryMaxAttempts,
+		BaseDelay:   config.RetryBaseDelay,
+		MaxDelay:    config.RetryMaxDelay,
+	})
+	api.SetRateLimit(config.RequestsPerSecond, config.RequestBurst, config.MaxInFlightRequests)
+
+	maxConcurrentQueries := config.MaxConcurrentQueries
+	if maxConcurrentQueries <= 0 {
+		maxConcurrentQueries = defaultMaxConcurrentQueries
+	}
+
 	return &Datasource{
-		baseURL: baseURL,
-		api:     NewApi(baseURL, config.Secrets.ApiKey, cacheTime, 10*time.Second),
+		baseURL:              baseURL,
+		api:                  api,
+		maxConcurrentQueries: maxConcurrentQueries,
+		resolveCache:         newTTLCache[[]metricTarget](cacheTime, defaultCacheMaxEntries, defaultCacheMaxBytes),
+		channelMetaCache:     newTTLCache[map[string]channelMeta](channelMetadataTTL, defaultCacheMaxEntries, defaultCacheMaxBytes),
+		channelLimitCache:    newTTLCache[*channelLimits](channelMetadataTTL, defaultCacheMaxEntries, defaultCacheMaxBytes),
 	}, nil
 }
 
 // Dispose, datasource ayarları değiştiğinde çağrılır.
 func (d *Datasource) Dispose() {
-	// Gerekirse kaynak temizleme işlemleri yapılabilir.
+	// Önbellekteki PRTG yanıtlarını bırakıyor ve boştaki bağlantıları kapatıyoruz.
+	d.api.ClearCache()
+	d.resolveCache.Clear()
+	d.channelMetaCache.Clear()
+	d.channelLimitCache.Clear()
+	d.api.CloseIdleConnections()
 }
 
-// QueryData, gelen sorguları işler ve sonuçları döner.
+// QueryData, gelen sorguları en fazla maxConcurrentQueries paralel işçiyle işler ve sonuçları döner.
+// Her sorgunun hatası kendi RefID'sine yazılır, diğer sorguları etkilemez.
 func (d *Datasource) QueryData(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
 	response := backend.NewQueryDataResponse()
 
-	// Her sorgu için query metodunu çağırıyoruz.
-	for _, q := range req.Queries {
-		res := d.query(ctx, req.PluginContext, q)
-		response.Responses[q.RefID] = res
+	workers := d.maxConcurrentQueries
+	if workers <= 0 {
+		workers = defaultMaxConcurrentQueries
+	}
+	if workers > len(req.Queries) {
+		workers = len(req.Queries)
+	}
+
+	// Sonuçlar sorgu sırasına göre saklanır, böylece yanıt deterministik kalır.
+	results := make([]backend.DataResponse, len(req.Queries))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = d.runQuery(ctx, req.PluginContext, req.Queries[i])
+			}
+		}()
+	}
+
+	for i := range req.Queries {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i, q := range req.Queries {
+		response.Responses[q.RefID] = results[i]
 	}
 
 	return response, nil
 }
 
+// runQuery, tek bir sorguyu çalıştırır; iptal edilmiş istekleri ve panikleri sorgu hatasına çevirir.
+func (d *Datasource) runQuery(ctx context.Context, pCtx backend.PluginContext, q backend.DataQuery) (res backend.DataResponse) {
+	// Grafana isteği iptal ettiyse kalan sorgular PRTG'ye gönderilmez.
+	if err := ctx.Err(); err != nil {
+		return backend.ErrDataResponse(backend.StatusTimeout, fmt.Sprintf("query cancelled: %v", err))
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			backend.Logger.Error("Query panicked", "refId", q.RefID, "panic", r)
+			res = backend.ErrDataResponse(backend.StatusInternal, fmt.Sprintf("internal error: %v", r))
+		}
+	}()
+
+	return d.query(ctx, pCtx, q)
+}
+
 // parsePRTGDateTime parses PRTG datetime strings in various formats
 func parsePRTGDateTime(datetime string) (time.Time, string, error) {
 	// Try different known PRTG date formats
@@ -99,24 +209,39 @@ func (d *Datasource) CheckHealth(ctx context.Context, req *backend.CheckHealthRe
 		return res, nil
 	}
 
-	// Check API key
-	if config.Secrets.ApiKey == "" {
+	// Check credentials for the configured auth mode
+	if err := config.ValidateAuth(); err != nil {
+		res.Status = backend.HealthStatusError
+		res.Message = fmt.Sprintf("Auth mode %s: %v", config.AuthMode, err)
+		return res, nil
+	}
+
+	// Check TLS settings
+	if _, err := newTLSConfig(config); err != nil {
 		res.Status = backend.HealthStatusError
-		res.Message = "API key is missing"
+		res.Message = fmt.Sprintf("Invalid TLS settings: %v", err)
 		return res, nil
 	}
 
 	// Get PRTG status including version
-	status, err := d.api.GetStatusList()
+	status, err := d.api.GetStatusList(ctx)
 	if err != nil {
+		if msg, ok := describeTLSError(err); ok {
+			res.Status = backend.HealthStatusError
+			res.Message = fmt.Sprintf("TLS error: %s", msg)
+			return res, nil
+		}
 		res.Status = backend.HealthStatusError
-		res.Message = fmt.Sprintf("Failed to get PRTG status: %v", err)
+		res.Message = fmt.Sprintf("Failed to get PRTG status (auth mode %s): %v", config.AuthMode, err)
 		return res, nil
 	}
 
-	// Return success with version information
+	// Return success with version and auth mode information
 	res.Status = backend.HealthStatusOk
-	res.Message = fmt.Sprintf("Data source is working. PRTG Version: %s", status.Version)
+	res.Message = fmt.Sprintf("Data source is working (auth mode: %s). PRTG Version: %s", config.AuthMode, status.Version)
+	if status.Overloadprotection {
+		res.Message += ". Warning: PRTG overload protection is active, consider lowering the request rate limit"
+	}
 	return res, nil
 }
 
@@ -125,11 +250,11 @@ func (d *Datasource) CallResource(ctx context.Context, req *backend.CallResource
 	pathParts := strings.Split(req.Path, "/")
 	switch pathParts[0] {
 	case "groups":
-		return d.handleGetGroups(sender)
+		return d.handleGetGroups(ctx, req, sender)
 	case "devices":
-		return d.handleGetDevices(sender)
+		return d.handleGetDevices(ctx, req, sender)
 	case "sensors":
-		return d.handleGetSensors(sender)
+		return d.handleGetSensors(ctx, req, sender)
 	case "channels":
 		if len(pathParts) < 2 {
 			errorResponse := map[string]string{"error": "missing objid parameter"}
@@ -140,79 +265,79 @@ func (d *Datasource) CallResource(ctx context.Context, req *backend.CallResource
 				Body:    errorJSON,
 			})
 		}
-		return d.handleGetChannel(sender, pathParts[1])
+		return d.handleGetChannel(ctx, sender, pathParts[1])
 	default:
 		return sender.Send(&backend.CallResourceResponse{Status: http.StatusNotFound})
 	}
 }
 
-func (d *Datasource) handleGetGroups(sender backend.CallResourceResponseSender) error {
-	groups, err := d.api.GetGroups()
+func (d *Datasource) handleGetGroups(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
+	query, err := parseResourceQuery(req.URL, "group")
 	if err != nil {
-		return sender.Send(&backend.CallResourceResponse{
-			Status: http.StatusInternalServerError,
-			Body:   []byte(err.Error()),
-		})
+		return sendResourceError(sender, http.StatusBadRequest, err.Error())
+	}
+	var groups *PrtgGroupListResponse
+	if query.paged() {
+		groups, err = d.api.GetGroupsPage(ctx, query.filter, query.offset, query.pageSize())
+	} else {
+		groups, err = d.api.GetGroups(ctx, query.filter)
 	}
-	body, err := json.Marshal(groups)
 	if err != nil {
-		return sender.Send(&backend.CallResourceResponse{
-			Status: http.StatusInternalServerError,
-			Body:   []byte(fmt.Sprintf("error marshaling groups: %v", err)),
-		})
+		return sendAPIError(sender, err)
 	}
-	return sender.Send(&backend.CallResourceResponse{
-		Status:  http.StatusOK,
-		Headers: map[string][]string{"Content-Type": {"application/json"}},
-		Body:    body,
-	})
+
+	objects := make([]resourceObject, len(groups.Groups))
+	for i, g := range groups.Groups {
+		objects[i] = resourceObject{Name: g.Group, ObjectId: g.ObjectId, ParentId: g.ParentId, Status: g.Status}
+	}
+	return sendResourceList(sender, "groups", groups.TreeSize, objects)
 }
 
-func (d *Datasource) handleGetDevices(sender backend.CallResourceResponseSender) error {
-	devices, err := d.api.GetDevices()
+func (d *Datasource) handleGetDevices(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
+	query, err := parseResourceQuery(req.URL, "device")
 	if err != nil {
-		return sender.Send(&backend.CallResourceResponse{
-			Status: http.StatusInternalServerError,
-			Body:   []byte(err.Error()),
-		})
+		return sendResourceError(sender, http.StatusBadRequest, err.Error())
+	}
+	var devices *PrtgDevicesListResponse
+	if query.paged() {
+		devices, err = d.api.GetDevicesPage(ctx, query.filter, query.offset, query.pageSize())
+	} else {
+		devices, err = d.api.GetDevices(ctx, query.filter)
 	}
-	body, err := json.Marshal(devices)
 	if err != nil {
-		return sender.Send(&backend.CallResourceResponse{
-			Status: http.StatusInternalServerError,
-			Body:   []byte(fmt.Sprintf("error marshaling devices: %v", err)),
-		})
+		return sendAPIError(sender, err)
 	}
-	return sender.Send(&backend.CallResourceResponse{
-		Status:  http.StatusOK,
-		Headers: map[string][]string{"Content-Type": {"application/json"}},
-		Body:    body,
-	})
+
+	objects := make([]resourceObject, len(devices.Devices))
+	for i, dev := range devices.Devices {
+		objects[i] = resourceObject{Name: dev.Device, ObjectId: dev.ObjectId, Parent: dev.Group, ParentId: dev.ParentId, Status: dev.Status}
+	}
+	return sendResourceList(sender, "devices", devices.TreeSize, objects)
 }
 
-func (d *Datasource) handleGetSensors(sender backend.CallResourceResponseSender) error {
-	sensors, err := d.api.GetSensors()
+func (d *Datasource) handleGetSensors(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
+	query, err := parseResourceQuery(req.URL, "sensor")
 	if err != nil {
-		return sender.Send(&backend.CallResourceResponse{
-			Status: http.StatusInternalServerError,
-			Body:   []byte(err.Error()),
-		})
+		return sendResourceError(sender, http.StatusBadRequest, err.Error())
+	}
+	var sensors *PrtgSensorsListResponse
+	if query.paged() {
+		sensors, err = d.api.GetSensorsPage(ctx, query.filter, query.offset, query.pageSize())
+	} else {
+		sensors, err = d.api.GetSensors(ctx, query.filter)
 	}
-	body, err := json.Marshal(sensors)
 	if err != nil {
-		return sender.Send(&backend.CallResourceResponse{
-			Status: http.StatusInternalServerError,
-			Body:   []byte(fmt.Sprintf("error marshaling sensors: %v", err)),
-		})
+		return sendAPIError(sender, err)
 	}
-	return sender.Send(&backend.CallResourceResponse{
-		Status:  http.StatusOK,
-		Headers: map[string][]string{"Content-Type": {"application/json"}},
-		Body:    body,
-	})
+
+	objects := make([]resourceObject, len(sensors.Sensors))
+	for i, s := range sensors.Sensors {
+		objects[i] = resourceObject{Name: s.Sensor, ObjectId: s.ObjectId, Parent: s.Device, ParentId: s.ParentId, Status: s.Status}
+	}
+	return sendResourceList(sender, "sensors", sensors.TreeSize, objects)
 }
 
-func (d *Datasource) handleGetChannel(sender backend.CallResourceResponseSender, objid string) error {
+func (d *Datasource) handleGetChannel(ctx context.Context, sender backend.CallResourceResponseSender, objid string) error {
 	if objid == "" {
 		errorResponse := map[string]string{"error": "missing objid parameter"}
 		errorJSON, _ := json.Marshal(errorResponse)
@@ -222,15 +347,9 @@ func (d *Datasource) handleGetChannel(sender backend.CallResourceResponseSender,
 			Body:    errorJSON,
 		})
 	}
-	channels, err := d.api.GetChannels(objid)
+	channels, err := d.api.GetChannels(ctx, objid)
 	if err != nil {
-		errorResponse := map[string]string{"error": err.Error()}
-		errorJSON, _ := json.Marshal(errorResponse)
-		return sender.Send(&backend.CallResourceResponse{
-			Status:  http.StatusInternalServerError,
-			Headers: map[string][]string{"Content-Type": {"application/json"}},
-			Body:    errorJSON,
-		})
+		return sendAPIError(sender, err)
 	}
 	body, err := json.Marshal(channels)
 	if err != nil {
@@ -0,0 +1,132 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+// Sentinel errors for failed PRTG API requests. They are wrapped in *APIError and can be
+// checked with errors.Is.
+var (
+	ErrUnauthorized      = errors.New("unauthorized")
+	ErrForbidden         = errors.New("forbidden")
+	ErrNotFound          = errors.New("not found")
+	ErrTimeout           = errors.New("timeout")
+	ErrOverloaded        = errors.New("PRTG overloaded")
+	ErrMalformedResponse = errors.New("malformed response")
+)
+
+// APIError describes a failed PRTG API request. Kind is one of the sentinel errors or nil
+// for other failures such as refused connections or unexpected status codes, Err is the
+// underlying cause. errors.Is matches both.
+type APIError struct {
+	Endpoint   string
+	StatusCode int
+	Kind       error
+	Err        error
+}
+
+func (e *APIError) Error() string {
+	msg := "PRTG API " + e.Endpoint
+	if e.StatusCode != 0 {
+		msg += fmt.Sprintf(" (HTTP %d)", e.StatusCode)
+	}
+	if e.Kind != nil {
+		msg += ": " + e.Kind.Error()
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns Kind and Err so errors.Is and errors.As see both.
+func (e *APIError) Unwrap() []error {
+	var errs []error
+	if e.Kind != nil {
+		errs = append(errs, e.Kind)
+	}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
+
+// statusCodeKind returns the sentinel error for an HTTP status code returned by PRTG, or
+// nil if the code has no specific meaning.
+func statusCodeKind(code int) error {
+	switch code {
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return ErrTimeout
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return ErrOverloaded
+	}
+	return nil
+}
+
+// transportErrorKind returns ErrTimeout for client, network and context timeouts and nil otherwise.
+func transportErrorKind(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrTimeout
+	}
+	return nil
+}
+
+// malformedResponse wraps a decoding error of a PRTG response.
+func malformedResponse(endpoint string, err error) error {
+	apiErr := &APIError{Endpoint: endpoint, Kind: ErrMalformedResponse, Err: err}
+	observeError(endpoint, apiErr)
+	return apiErr
+}
+
+// errorStatus maps an error to the Grafana status reported for it and whether it was
+// caused by PRTG rather than by the plugin or the query.
+func errorStatus(err error) (backend.Status, bool) {
+	switch {
+	case errors.Is(err, ErrUnauthorized):
+		return backend.StatusUnauthorized, true
+	case errors.Is(err, ErrForbidden):
+		return backend.StatusForbidden, true
+	case errors.Is(err, ErrNotFound):
+		return backend.StatusNotFound, true
+	case errors.Is(err, ErrTimeout):
+		return backend.StatusTimeout, true
+	case errors.Is(err, ErrOverloaded):
+		return backend.StatusTooManyRequests, true
+	case errors.Is(err, ErrMalformedResponse):
+		return backend.StatusBadGateway, true
+	case errors.Is(err, context.Canceled):
+		return backend.StatusTimeout, false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return backend.StatusBadGateway, true
+	}
+	return backend.StatusBadRequest, false
+}
+
+// apiErrorResponse returns the data response for a failed API call. PRTG failures are
+// marked as downstream errors so they can be told apart from plugin errors.
+func apiErrorResponse(message string, err error) backend.DataResponse {
+	status, downstream := errorStatus(err)
+	text := fmt.Sprintf("%s: %v", message, err)
+	if downstream {
+		return backend.ErrDataResponseWithSource(status, backend.ErrorSourceDownstream, text)
+	}
+	return backend.ErrDataResponse(status, text)
+}
@@ -0,0 +1,125 @@
+package plugin
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// PRTG object states accepted by filter_status.
+const (
+	prtgStatusUnknown            = 1
+	prtgStatusCollecting         = 2
+	prtgStatusUp                 = 3
+	prtgStatusWarning            = 4
+	prtgStatusDown               = 5
+	prtgStatusNoProbe            = 6
+	prtgStatusPausedByUser       = 7
+	prtgStatusPausedByDependency = 8
+	prtgStatusPausedBySchedule   = 9
+	prtgStatusUnusual            = 10
+	prtgStatusNotLicensed        = 11
+	prtgStatusPausedUntil        = 12
+	prtgStatusDownAcknowledged   = 13
+	prtgStatusDownPartial        = 14
+)
+
+// TableFilter builds the filter_* parameters of a table.json request so PRTG only returns
+// matching rows. Values given to the same filter are combined with OR by PRTG, different
+// filters with AND. A nil *TableFilter filters nothing.
+type TableFilter struct {
+	values url.Values
+}
+
+// NewTableFilter creates an empty filter.
+func NewTableFilter() *TableFilter {
+	return &TableFilter{values: url.Values{}}
+}
+
+// Status restricts the rows to the given PRTG states, e.g. prtgStatusDown.
+func (f *TableFilter) Status(states ...int) *TableFilter {
+	for _, state := range states {
+		f.values.Add("filter_status", strconv.Itoa(state))
+	}
+	return f
+}
+
+// Tags restricts the rows to objects carrying any of the given tags.
+func (f *TableFilter) Tags(tags ...string) *TableFilter {
+	if len(tags) > 0 {
+		f.values.Add("filter_tags", "@tag("+strings.Join(tags, ",")+")")
+	}
+	return f
+}
+
+// ObjectIds restricts the rows to the given objids.
+func (f *TableFilter) ObjectIds(objids ...string) *TableFilter {
+	for _, objid := range objids {
+		f.values.Add("filter_objid", objid)
+	}
+	return f
+}
+
+// ParentIds restricts the rows to children of the given objids.
+func (f *TableFilter) ParentIds(objids ...string) *TableFilter {
+	for _, objid := range objids {
+		f.values.Add("filter_parentid", objid)
+	}
+	return f
+}
+
+// Within restricts the rows to objects below the given group or device, including nested groups.
+func (f *TableFilter) Within(objid string) *TableFilter {
+	f.values.Set("id", objid)
+	return f
+}
+
+// Equals restricts the rows to those whose column equals value, e.g. Equals("device", "fw-01").
+func (f *TableFilter) Equals(column, value string) *TableFilter {
+	f.values.Add("filter_"+column, value)
+	return f
+}
+
+// Contains restricts the rows to those whose column contains substr.
+func (f *TableFilter) Contains(column, substr string) *TableFilter {
+	f.values.Add("filter_"+column, "@sub("+substr+")")
+	return f
+}
+
+// apply adds the filter parameters to the request parameters.
+func (f *TableFilter) apply(params url.Values) {
+	if f == nil {
+		return
+	}
+	for key, values := range f.values {
+		params[key] = append(params[key], values...)
+	}
+}
+
+// objectFilter returns the server-side filter for objects selected by name in column and
+// by tag filter. It only narrows the rows; callers still match the returned objects.
+func objectFilter(column, name string, tagFilter tagExpr) *TableFilter {
+	filter := NewTableFilter()
+	if name != "" {
+		filter.Equals(column, name)
+	}
+	if tags := requiredTags(tagFilter); len(tags) > 0 {
+		filter.Tags(tags...)
+	}
+	return filter
+}
+
+// parentFilter returns the server-side filter for the children of parent, which may be an
+// objid or the name of the parent as found in parentColumn. An empty parent filters nothing.
+func parentFilter(parent, parentColumn string) *TableFilter {
+	if parent == "" {
+		return nil
+	}
+	if _, err := strconv.ParseInt(parent, 10, 64); err == nil {
+		return NewTableFilter().ParentIds(parent)
+	}
+	if parentColumn == "" {
+		return nil
+	}
+	return NewTableFilter().Equals(parentColumn, parent)
+}
@@ -0,0 +1,78 @@
+package plugin
+
+import (
+	"time"
+)
+
+const (
+	// historicMaxRows is the count sent with every historicdata.json request. A chunk
+	// returning this many rows was cut off by PRTG.
+	historicMaxRows = 50000
+	// historicChunkRows is the number of rows a chunk is sized for. It stays well below
+	// historicMaxRows so sensors scanning faster than rawScanInterval still fit.
+	historicChunkRows = 10000
+	// historicMaxChunks bounds the number of requests per sensor and range.
+	historicMaxChunks = 50
+	// historicChunkConcurrency limits the chunk requests of one sensor in flight.
+	historicChunkConcurrency = 3
+	// rawScanInterval is the scanning interval assumed when sizing chunks of raw data.
+	rawScanInterval = 60 * time.Second
+)
+
+// historicChunk is a part of the requested time range fetched with a single request.
+type historicChunk struct {
+	start time.Time
+	end   time.Time
+}
+
+// historicChunks splits [start, end] into consecutive chunks that each hold about
+// historicChunkRows rows at the given averaging interval. Chunks are widened when the
+// range would need more than historicMaxChunks requests.
+func historicChunks(start, end time.Time, avg int64) []historicChunk {
+	step := rawScanInterval
+	if avg > 0 {
+		step = time.Duration(avg) * time.Second
+	}
+	span := step * historicChunkRows
+	if total := end.Sub(start); total > span*historicMaxChunks {
+		span = (total + historicMaxChunks - 1) / historicMaxChunks
+	}
+
+	var chunks []historicChunk
+	for chunkStart := start; chunkStart.Before(end); chunkStart = chunkStart.Add(span) {
+		chunkEnd := chunkStart.Add(span)
+		if chunkEnd.After(end) {
+			chunkEnd = end
+		}
+		chunks = append(chunks, historicChunk{start: chunkStart, end: chunkEnd})
+	}
+	return chunks
+}
+
+// mergeHistoricChunks concatenates the chunk responses in order and drops rows whose
+// timestamp was already returned by a previous chunk, as adjacent chunks share their
+// boundary. The result is marked truncated if any chunk hit historicMaxRows.
+func mergeHistoricChunks(parts []*PrtgHistoricalDataResponse) *PrtgHistoricalDataResponse {
+	merged := &PrtgHistoricalDataResponse{}
+	seen := make(map[string]bool)
+	for _, part := range parts {
+		if part == nil {
+			continue
+		}
+		if merged.PrtgVersion == "" {
+			merged.PrtgVersion = part.PrtgVersion
+		}
+		if len(part.HistData) >= historicMaxRows {
+			merged.Truncated = true
+		}
+		for _, item := range part.HistData {
+			if seen[item.Datetime] {
+				continue
+			}
+			seen[item.Datetime] = true
+			merged.HistData = append(merged.HistData, item)
+		}
+	}
+	merged.TreeSize = int64(len(merged.HistData))
+	return merged
+}
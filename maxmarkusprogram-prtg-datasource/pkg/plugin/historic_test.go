@@ -0,0 +1,145 @@
+package plugin
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHistoricChunks(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	rawSpan := rawScanInterval * historicChunkRows
+
+	tests := []struct {
+		name      string
+		end       time.Time
+		avg       int64
+		wantCount int
+		wantSpan  time.Duration
+	}{
+		{name: "empty range", end: start, wantCount: 0},
+		{name: "one hour raw", end: start.Add(time.Hour), wantCount: 1, wantSpan: rawSpan},
+		{name: "exactly one raw chunk", end: start.Add(rawSpan), wantCount: 1, wantSpan: rawSpan},
+		{name: "two and a half raw chunks", end: start.Add(rawSpan * 5 / 2), wantCount: 3, wantSpan: rawSpan},
+		{name: "hourly averages", end: start.Add(3 * 10000 * time.Hour), avg: 3600, wantCount: 3, wantSpan: 10000 * time.Hour},
+		{name: "limit reached", end: start.Add(rawSpan * historicMaxChunks), wantCount: historicMaxChunks, wantSpan: rawSpan},
+		{name: "widened raw", end: start.Add(rawSpan * historicMaxChunks * 3), wantCount: historicMaxChunks, wantSpan: rawSpan * 3},
+		{name: "widened uneven", end: start.Add(rawSpan*historicMaxChunks + time.Minute), wantCount: historicMaxChunks},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := historicChunks(start, tt.end, tt.avg)
+			if len(chunks) != tt.wantCount {
+				t.Fatalf("got %d chunks, want %d", len(chunks), tt.wantCount)
+			}
+			if len(chunks) == 0 {
+				return
+			}
+
+			// Chunks cover the range without gaps; each but the last has the same span.
+			if !chunks[0].start.Equal(start) || !chunks[len(chunks)-1].end.Equal(tt.end) {
+				t.Fatalf("chunks cover %v - %v, want %v - %v", chunks[0].start, chunks[len(chunks)-1].end, start, tt.end)
+			}
+			span := chunks[0].end.Sub(chunks[0].start)
+			if tt.wantSpan > 0 && len(chunks) > 1 && span != tt.wantSpan {
+				t.Fatalf("span = %v, want %v", span, tt.wantSpan)
+			}
+			for i, chunk := range chunks {
+				if !chunk.end.After(chunk.start) {
+					t.Fatalf("chunk %d is empty: %v - %v", i, chunk.start, chunk.end)
+				}
+				if i > 0 && !chunk.start.Equal(chunks[i-1].end) {
+					t.Fatalf("chunk %d starts at %v, previous ends at %v", i, chunk.start, chunks[i-1].end)
+				}
+				if i < len(chunks)-1 && chunk.end.Sub(chunk.start) != span {
+					t.Fatalf("chunk %d spans %v, want %v", i, chunk.end.Sub(chunk.start), span)
+				}
+			}
+		})
+	}
+}
+
+// historicPart builds a chunk response with one row per timestamp.
+func historicPart(datetimes ...string) *PrtgHistoricalDataResponse {
+	part := &PrtgHistoricalDataResponse{PrtgVersion: "24.1"}
+	for _, datetime := range datetimes {
+		part.HistData = append(part.HistData, PrtgValues{Datetime: datetime, Value: map[string]interface{}{"v": datetime}})
+	}
+	return part
+}
+
+// datetimes returns n distinct timestamps.
+func datetimes(n int) []string {
+	values := make([]string, n)
+	for i := range values {
+		values[i] = fmt.Sprintf("row %d", i)
+	}
+	return values
+}
+
+func TestMergeHistoricChunks(t *testing.T) {
+	tests := []struct {
+		name          string
+		parts         []*PrtgHistoricalDataResponse
+		wantRows      []string
+		wantTruncated bool
+	}{
+		{name: "no parts", parts: nil, wantRows: nil},
+		{name: "single part", parts: []*PrtgHistoricalDataResponse{historicPart("1", "2")}, wantRows: []string{"1", "2"}},
+		{
+			name:     "shared boundary",
+			parts:    []*PrtgHistoricalDataResponse{historicPart("1", "2", "3"), historicPart("3", "4"), historicPart("4", "5")},
+			wantRows: []string{"1", "2", "3", "4", "5"},
+		},
+		{
+			name:     "skips missing parts",
+			parts:    []*PrtgHistoricalDataResponse{nil, historicPart("1"), nil, historicPart("2")},
+			wantRows: []string{"1", "2"},
+		},
+		{
+			name:     "empty part",
+			parts:    []*PrtgHistoricalDataResponse{historicPart("1"), historicPart(), historicPart("2")},
+			wantRows: []string{"1", "2"},
+		},
+		{
+			name:          "truncated part",
+			parts:         []*PrtgHistoricalDataResponse{historicPart("a"), historicPart(datetimes(historicMaxRows)...)},
+			wantRows:      append([]string{"a"}, datetimes(historicMaxRows)...),
+			wantTruncated: true,
+		},
+		{
+			name:     "one row below the limit",
+			parts:    []*PrtgHistoricalDataResponse{historicPart(datetimes(historicMaxRows - 1)...)},
+			wantRows: datetimes(historicMaxRows - 1),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := mergeHistoricChunks(tt.parts)
+
+			var rows []string
+			for _, item := range merged.HistData {
+				if item.Value["v"] != item.Datetime {
+					t.Fatalf("row %q lost its values", item.Datetime)
+				}
+				rows = append(rows, item.Datetime)
+			}
+			if !reflect.DeepEqual(rows, tt.wantRows) {
+				if len(rows) > 10 || len(tt.wantRows) > 10 {
+					t.Fatalf("got %d rows, want %d", len(rows), len(tt.wantRows))
+				}
+				t.Fatalf("rows = %q, want %q", rows, tt.wantRows)
+			}
+			if merged.TreeSize != int64(len(tt.wantRows)) {
+				t.Fatalf("TreeSize = %d, want %d", merged.TreeSize, len(tt.wantRows))
+			}
+			if merged.Truncated != tt.wantTruncated {
+				t.Fatalf("Truncated = %v, want %v", merged.Truncated, tt.wantTruncated)
+			}
+			if len(tt.wantRows) > 0 && merged.PrtgVersion != "24.1" {
+				t.Fatalf("PrtgVersion = %q, want 24.1", merged.PrtgVersion)
+			}
+		})
+	}
+}
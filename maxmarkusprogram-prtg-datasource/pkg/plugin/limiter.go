@@ -0,0 +1,70 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultRequestsPerSecond   = 10
+	defaultRequestBurst        = 20
+	defaultMaxInFlightRequests = 10
+)
+
+// requestLimiter throttles the requests of one datasource instance toward PRTG with a
+// token bucket and caps the number of requests in flight, so large dashboards cannot
+// push the PRTG web server into overload protection. A nil limiter does not limit.
+type requestLimiter struct {
+	rate     *rate.Limiter
+	inFlight chan struct{}
+}
+
+// newRequestLimiter creates a limiter allowing requestsPerSecond with the given burst and at
+// most maxInFlight concurrent requests. Zero values use the defaults, negative values
+// disable the respective limit.
+func newRequestLimiter(requestsPerSecond float64, burst, maxInFlight int) *requestLimiter {
+	if requestsPerSecond == 0 {
+		requestsPerSecond = defaultRequestsPerSecond
+	}
+	if burst == 0 {
+		burst = defaultRequestBurst
+	}
+	if maxInFlight == 0 {
+		maxInFlight = defaultMaxInFlightRequests
+	}
+
+	l := &requestLimiter{}
+	if requestsPerSecond > 0 {
+		l.rate = rate.NewLimiter(rate.Limit(requestsPerSecond), int(math.Max(float64(burst), 1)))
+	}
+	if maxInFlight > 0 {
+		l.inFlight = make(chan struct{}, maxInFlight)
+	}
+	return l
+}
+
+// acquire waits for a rate limit token and a free request slot. It returns early with an
+// error when ctx is done or its deadline would pass before a token is available. On success
+// the returned function must be called once the request is finished.
+func (l *requestLimiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	if l.rate != nil {
+		if err := l.rate.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("waiting for rate limit: %w", err)
+		}
+	}
+	if l.inFlight == nil {
+		return func() {}, nil
+	}
+	select {
+	case l.inFlight <- struct{}{}:
+		return func() { <-l.inFlight }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for a free request slot: %w", ctx.Err())
+	}
+}
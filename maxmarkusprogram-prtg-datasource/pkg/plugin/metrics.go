@@ -0,0 +1,321 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// allChannels selects every channel of a sensor when used as channel name.
+const allChannels = "*"
+
+// handleMetricsQuery fetches the historic data of every sensor selected by the query
+// and returns one labeled frame per sensor and selected channel, where all channels
+// of a sensor are taken from the same historicdata.json response.
+func (d *Datasource) handleMetricsQuery(ctx context.Context, qm queryModel, query backend.DataQuery) backend.DataResponse {
+	var response backend.DataResponse
+
+	targets, err := d.resolveTargets(ctx, qm)
+	if err != nil {
+		backend.Logger.Error("Sensor resolution failed", "error", err)
+		return apiErrorResponse("sensor selection failed", err)
+	}
+	if len(targets) == 0 {
+		return backend.ErrDataResponse(backend.StatusBadRequest, "no sensors match the selection")
+	}
+
+	avg, err := averagingInterval(qm, query)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+
+	fromTime := query.TimeRange.From.UnixMilli()
+	toTime := query.TimeRange.To.UnixMilli()
+	results := d.fetchHistoricalData(ctx, qm, targets, fromTime, toTime, avg)
+
+	multi := qm.isMultiObjectQuery()
+	var notices []data.Notice
+	var firstErr error
+	for i, target := range targets {
+		if err := results[i].err; err != nil {
+			backend.Logger.Error("API request failed", "objid", target.ObjectId, "error", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			notices = append(notices, data.Notice{
+				Severity: data.NoticeSeverityWarning,
+				Text:     fmt.Sprintf("%s (%s): %v", target.Sensor, target.ObjectId, err),
+			})
+			continue
+		}
+
+		if results[i].data.Truncated {
+			notices = append(notices, data.Notice{
+				Severity: data.NoticeSeverityWarning,
+				Text: fmt.Sprintf("%s (%s): PRTG returned at most %d rows per request, the data is incomplete; narrow the time range or increase averaging",
+					target.Sensor, target.ObjectId, historicMaxRows),
+			})
+		}
+
+		channels := selectChannels(qm, results[i].data)
+		if len(channels) == 0 {
+			notices = append(notices, data.Notice{
+				Severity: data.NoticeSeverityWarning,
+				Text:     fmt.Sprintf("%s (%s): no channel selected", target.Sensor, target.ObjectId),
+			})
+			continue
+		}
+		response.Frames = append(response.Frames, buildMetricsFrames(qm, target, results[i].data, channels, results[i].metas, multi)...)
+	}
+
+	if len(response.Frames) == 0 {
+		if firstErr == nil {
+			return backend.ErrDataResponse(backend.StatusBadRequest, "no channel selected")
+		}
+		return apiErrorResponse("API request failed", firstErr)
+	}
+	if multi {
+		// Expose the resolved objids for debugging in the query inspector.
+		objids := make([]string, len(targets))
+		for i, target := range targets {
+			objids[i] = target.ObjectId
+		}
+		for _, frame := range response.Frames {
+			frame.Meta.Custom = map[string]interface{}{"resolvedObjids": objids}
+		}
+	}
+	if avg > 0 {
+		notices = append(notices, data.Notice{
+			Severity: data.NoticeSeverityInfo,
+			Text:     fmt.Sprintf("Values are averaged by PRTG over %s intervals", formatAvgInterval(avg)),
+		})
+	}
+	if len(notices) > 0 {
+		response.Frames[0].AppendNotices(notices...)
+	}
+	return response
+}
+
+// historicalResult holds the historic data and channel metadata or the error of a single sensor.
+type historicalResult struct {
+	data  *PrtgHistoricalDataResponse
+	metas map[string]channelMeta
+	err   error
+}
+
+// fetchHistoricalData loads the historic data and channel metadata of all targets with at
+// most maxConcurrentQueries sensors in flight. Results keep the order of targets.
+func (d *Datasource) fetchHistoricalData(ctx context.Context, qm queryModel, targets []metricTarget, fromTime, toTime, avg int64) []historicalResult {
+	results := make([]historicalResult, len(targets))
+
+	limit := d.maxConcurrentQueries
+	if limit <= 0 {
+		limit = defaultMaxConcurrentQueries
+	}
+	sem := make(chan struct{}, limit)
+
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target metricTarget) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				results[i].err = ctx.Err()
+				return
+			}
+			results[i].data, results[i].err = d.api.GetHistoricalData(ctx, target.ObjectId, fromTime, toTime, avg)
+			if results[i].err == nil {
+				results[i].metas = d.channelMetadata(ctx, target.ObjectId, selectChannels(qm, results[i].data))
+			}
+		}(i, target)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// selectChannels returns the channels requested by the query. Channels takes precedence
+// over Channel, and "*" in either expands to every channel found in the response.
+func selectChannels(qm queryModel, historicalData *PrtgHistoricalDataResponse) []string {
+	requested := qm.Channels
+	if len(requested) == 0 && qm.Channel != "" {
+		requested = []string{qm.Channel}
+	}
+
+	available := availableChannels(historicalData)
+	for _, channel := range requested {
+		if channel == allChannels {
+			return available
+		}
+	}
+
+	var channels []string
+	for _, channel := range requested {
+		channels = append(channels, historicCaptions(channel, available)...)
+	}
+	return channels
+}
+
+// historicCaptions maps a channel name to its captions in historic data. Names from the
+// channel table, e.g. of channel variables, lack the "(speed)" and "(volume)" suffixes of
+// traffic channels; such a name selects all of its captions. Unknown names are kept.
+func historicCaptions(channel string, available []string) []string {
+	var captions []string
+	for _, caption := range available {
+		if caption == channel {
+			return []string{channel}
+		}
+		if rest, ok := strings.CutPrefix(caption, channel+" ("); ok && strings.HasSuffix(rest, ")") {
+			captions = append(captions, caption)
+		}
+	}
+	if len(captions) == 0 {
+		return []string{channel}
+	}
+	return captions
+}
+
+// availableChannels lists the channel names contained in historic data in sorted order.
+// Raw duplicates ("<name>(RAW)") are skipped when the formatted channel exists.
+func availableChannels(historicalData *PrtgHistoricalDataResponse) []string {
+	seen := make(map[string]bool)
+	for _, item := range historicalData.HistData {
+		for key := range item.Value {
+			seen[key] = true
+		}
+	}
+
+	channels := make([]string, 0, len(seen))
+	for key := range seen {
+		switch key {
+		case "datetime_raw", "coverage", "coverage_raw":
+			continue
+		}
+		if base, ok := strings.CutSuffix(key, "(RAW)"); ok && seen[strings.TrimSpace(base)] {
+			continue
+		}
+		channels = append(channels, key)
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// buildMetricsFrames converts historic data into frames following the dataplane
+// "timeseries-multi" contract: one frame per channel with a time field and a nullable
+// value field labeled with group, device, sensor, channel and objid, so alert rules
+// evaluate every sensor and channel as its own series. Units and decimals come from the
+// channel metadata in metas. Missing or unparsable values become null.
+func buildMetricsFrames(qm queryModel, target metricTarget, historicalData *PrtgHistoricalDataResponse, channels []string, metas map[string]channelMeta, multi bool) []*data.Frame {
+	times := make([]time.Time, 0, len(historicalData.HistData))
+	values := make([][]*float64, len(channels))
+
+	for _, item := range historicalData.HistData {
+		parsedTime, _, err := parsePRTGDateTime(item.Datetime)
+		if err != nil {
+			backend.Logger.Warn("Date parsing failed", "datetime", item.Datetime, "error", err)
+			continue
+		}
+		times = append(times, parsedTime)
+
+		for i, channel := range channels {
+			val, ok := item.Value[channel]
+			if !ok {
+				backend.Logger.Warn("Channel not found in item.Value", "channel", channel)
+				values[i] = append(values[i], nil)
+				continue
+			}
+			if floatVal, ok := toFloat64(val); ok {
+				values[i] = append(values[i], &floatVal)
+			} else {
+				values[i] = append(values[i], nil)
+			}
+		}
+	}
+
+	frames := make([]*data.Frame, 0, len(channels))
+	for i, channel := range channels {
+		config := &data.FieldConfig{
+			DisplayNameFromDS: metricsDisplayName(qm, target, channel, multi),
+		}
+		if meta, ok := lookupChannelMeta(metas, channel); ok {
+			applyChannelMeta(config, meta)
+		}
+		valueField := data.NewField("Value", metricLabels(target, channel), values[i]).SetConfig(config)
+		frame := data.NewFrame("response", data.NewField("Time", nil, times), valueField)
+		frame.SetMeta(&data.FrameMeta{
+			Type:        data.FrameTypeTimeSeriesMulti,
+			TypeVersion: data.FrameTypeVersion{0, 1},
+		})
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+// metricLabels returns the labels identifying a channel of a sensor.
+func metricLabels(target metricTarget, channel string) data.Labels {
+	labels := data.Labels{"channel": channel}
+	if target.Group != "" {
+		labels["group"] = target.Group
+	}
+	if target.Device != "" {
+		labels["device"] = target.Device
+	}
+	if target.Sensor != "" {
+		labels["sensor"] = target.Sensor
+	}
+	if target.ObjectId != "" {
+		labels["objid"] = target.ObjectId
+	}
+	return labels
+}
+
+// metricsDisplayName joins the optional group, device and sensor names with the channel name.
+// Multi-object queries include device and sensor names unless the query selects names
+// explicitly, so series of different sensors stay distinguishable.
+func metricsDisplayName(qm queryModel, target metricTarget, channel string, multi bool) string {
+	includeGroup, includeDevice, includeSensor := qm.IncludeGroupName, qm.IncludeDeviceName, qm.IncludeSensorName
+	if multi && !includeGroup && !includeDevice && !includeSensor {
+		includeDevice, includeSensor = true, true
+	}
+
+	var parts []string
+	if includeGroup && target.Group != "" {
+		parts = append(parts, target.Group)
+	}
+	if includeDevice && target.Device != "" {
+		parts = append(parts, target.Device)
+	}
+	if includeSensor && target.Sensor != "" {
+		parts = append(parts, target.Sensor)
+	}
+	parts = append(parts, channel)
+	return strings.Join(parts, " - ")
+}
+
+// toFloat64 converts a historic data value into a float64.
+func toFloat64(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case float64:
+		return v, true
+	case string:
+		floatVal, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			backend.Logger.Warn("Cannot convert value to float64", "value", v, "error", err)
+			return 0, false
+		}
+		return floatVal, true
+	default:
+		backend.Logger.Warn("Unexpected value type", "type", fmt.Sprintf("%T", v), "value", v)
+		return 0, false
+	}
+}
@@ -0,0 +1,35 @@
+package plugin
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelectChannels(t *testing.T) {
+	historicalData := &PrtgHistoricalDataResponse{HistData: []PrtgValues{{Value: map[string]interface{}{
+		"Traffic In (volume)":   1.0,
+		"Traffic In (speed)":    2.0,
+		"Traffic Total (speed)": 3.0,
+		"Downtime":              0.0,
+		"Downtime(RAW)":         0.0,
+	}}}}
+
+	tests := []struct {
+		name string
+		qm   queryModel
+		want []string
+	}{
+		{name: "caption", qm: queryModel{Channel: "Traffic In (speed)"}, want: []string{"Traffic In (speed)"}},
+		{name: "channel table name", qm: queryModel{Channel: "Traffic In"}, want: []string{"Traffic In (speed)", "Traffic In (volume)"}},
+		{name: "channels take precedence", qm: queryModel{Channel: "Downtime", Channels: []string{"Traffic Total"}}, want: []string{"Traffic Total (speed)"}},
+		{name: "unknown name", qm: queryModel{Channel: "Traffic"}, want: []string{"Traffic"}},
+		{name: "all channels", qm: queryModel{Channels: []string{"Downtime", "*"}}, want: []string{"Downtime", "Traffic In (speed)", "Traffic In (volume)", "Traffic Total (speed)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := selectChannels(tt.qm, historicalData); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("selectChannels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
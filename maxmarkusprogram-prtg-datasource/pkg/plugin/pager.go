@@ -0,0 +1,139 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+)
+
+// tablePageSize is the number of rows requested per table.json page.
+const tablePageSize = 5000
+
+// tablePager iterates over the rows of a PRTG table page by page using start/count.
+// Call Next until it returns false, read each page with Page and check Err afterwards:
+//
+//	pager := newTablePager[PrtgSensorListItemStruct](api, "sensors", params)
+//	for pager.Next(ctx) {
+//		for _, sensor := range pager.Page() { ... }
+//	}
+//	if err := pager.Err(); err != nil { ... }
+type tablePager[T any] struct {
+	api      *Api
+	content  string
+	params   url.Values
+	pageSize int
+
+	start    int
+	treeSize int64
+	version  string
+	page     []T
+	err      error
+	done     bool
+}
+
+// newTablePager creates a pager over table.json for the given content type, e.g. "sensors".
+// params holds additional request parameters such as columns and filters.
+func newTablePager[T any](api *Api, content string, params url.Values) *tablePager[T] {
+	return &tablePager[T]{
+		api:      api,
+		content:  content,
+		params:   params,
+		pageSize: tablePageSize,
+		treeSize: -1,
+	}
+}
+
+// Next fetches the next page and reports whether it holds any rows. Paging stops once
+// treesize rows were read, a page comes back short, or a request fails.
+func (p *tablePager[T]) Next(ctx context.Context) bool {
+	if p.done || p.err != nil {
+		return false
+	}
+	if p.treeSize >= 0 && int64(p.start) >= p.treeSize {
+		p.done = true
+		return false
+	}
+
+	page, treeSize, version, err := fetchTablePage[T](ctx, p.api, p.content, p.params, p.start, p.pageSize)
+	if err != nil {
+		p.err = err
+		return false
+	}
+
+	p.version = version
+	p.treeSize = treeSize
+	p.page = page
+	p.start += len(page)
+	if len(page) < p.pageSize {
+		p.done = true
+	}
+	return len(page) > 0
+}
+
+// Page returns the rows fetched by the last call to Next.
+func (p *tablePager[T]) Page() []T {
+	return p.page
+}
+
+// Err returns the error that stopped paging, if any.
+func (p *tablePager[T]) Err() error {
+	return p.err
+}
+
+// TreeSize returns the total number of rows reported by PRTG, or -1 before the first page.
+func (p *tablePager[T]) TreeSize() int64 {
+	return p.treeSize
+}
+
+// Version returns the PRTG version reported with the last page.
+func (p *tablePager[T]) Version() string {
+	return p.version
+}
+
+// fetchTablePage requests count rows of a table starting at row start and returns them
+// together with treesize and the PRTG version.
+func fetchTablePage[T any](ctx context.Context, api *Api, content string, params url.Values, start, count int) ([]T, int64, string, error) {
+	pageParams := make(url.Values, len(params)+3)
+	for key, values := range params {
+		pageParams[key] = values
+	}
+	pageParams.Set("content", content)
+	pageParams.Set("start", strconv.Itoa(start))
+	pageParams.Set("count", strconv.Itoa(count))
+
+	body, err := api.baseExecuteRequest(ctx, "table.json", pageParams)
+	if err != nil {
+		return nil, 0, "", err
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, 0, "", malformedResponse("table.json", err)
+	}
+	var treeSize int64
+	var version string
+	var page []T
+	for key, target := range map[string]interface{}{"treesize": &treeSize, "prtg-version": &version, content: &page} {
+		if value, ok := raw[key]; ok {
+			if err := json.Unmarshal(value, target); err != nil {
+				return nil, 0, "", malformedResponse("table.json", err)
+			}
+		}
+	}
+	return page, treeSize, version, nil
+}
+
+// collectTable reads all pages of a table and returns the rows together with treesize
+// and the PRTG version.
+func collectTable[T any](ctx context.Context, api *Api, content string, params url.Values) ([]T, int64, string, error) {
+	pager := newTablePager[T](api, content, params)
+	var rows []T
+	for pager.Next(ctx) {
+		rows = append(rows, pager.Page()...)
+	}
+	if err := pager.Err(); err != nil {
+		return nil, 0, "", err
+	}
+	return rows, pager.TreeSize(), pager.Version(), nil
+}
@@ -1,38 +1,117 @@
 package plugin
 
 import (
-	"crypto/tls"
+	"context"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"os"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/maxmarkusprogram/prtg/pkg/models"
 )
 
+// cacheableEndpoints enthält die Endpunkte, deren Antworten zwischengespeichert werden.
+var cacheableEndpoints = map[string]bool{
+	"table.json":            true,
+	"historicdata.json":     true,
+	"getobjectproperty.htm": true,
+}
+
+// objectColumns sind die Spalten, die für Gruppen, Geräte und Sensoren abgefragt werden.
+const objectColumns = "active,channel,datetime,device,group,message,objid,parentid,priority,sensor,status,tags"
+
+// credentialParams enthält die Query-Parameter, die Zugangsdaten transportieren.
+var credentialParams = map[string]bool{
+	"apitoken": true,
+	"username": true,
+	"passhash": true,
+	"password": true,
+}
+
+// Credentials enthält die Zugangsdaten für die PRTG-API.
+// Mode entspricht einem der models.AuthMode*-Werte.
+type Credentials struct {
+	Mode     string
+	ApiKey   string
+	Username string
+	Passhash string
+	Password string
+}
+
+// apply setzt die zum Modus passenden Zugangsdaten in die Query-Parameter.
+func (c Credentials) apply(q url.Values) {
+	switch c.Mode {
+	case models.AuthModePasshash:
+		q.Set("username", c.Username)
+		q.Set("passhash", c.Passhash)
+	case models.AuthModePassword:
+		q.Set("username", c.Username)
+		q.Set("password", c.Password)
+	default:
+		q.Set("apitoken", c.ApiKey)
+	}
+}
+
 // Api hält API-bezogene Konfigurationen.
 type Api struct {
 	baseURL string
-	apiKey  string
-	timeout time.Duration
+	auth    Credentials
+	client  *http.Client
+	cache   *ttlCache[[]byte]
+	retry   RetryPolicy
+	limiter *requestLimiter
+	// requests fasst gleichzeitige identische HTTP-Anfragen zusammen, results gleichzeitige
+	// Abrufe derselben dekodierten Objektlisten und historischen Daten.
+	requests flightGroup
+	results  flightGroup
 }
 
 // NewApi erstellt eine neue Api-Instanz.
-// Hier wird requestTimeout als Timeout für API-Anfragen genutzt.
-func NewApi(baseURL, apiKey string, cacheTime, requestTimeout time.Duration) *Api {
+// client wird für alle Anfragen wiederverwendet (Verbindungen, TLS-Sessions, Timeouts),
+// cacheTime bestimmt, wie lange Antworten zwischengespeichert werden.
+func NewApi(baseURL string, auth Credentials, client *http.Client, cacheTime time.Duration) *Api {
 	return &Api{
 		baseURL: baseURL,
-		apiKey:  apiKey,
-		timeout: requestTimeout,
+		auth:    auth,
+		client:  client,
+		cache:   newTTLCache[[]byte](cacheTime, defaultCacheMaxEntries, defaultCacheMaxBytes),
 	}
 }
 
+// CloseIdleConnections schließt alle ungenutzten Verbindungen des HTTP-Clients.
+func (a *Api) CloseIdleConnections() {
+	a.client.CloseIdleConnections()
+}
+
+// ClearCache verwirft alle zwischengespeicherten Antworten.
+func (a *Api) ClearCache() {
+	a.cache.Clear()
+}
+
+// buildCacheKey erstellt einen Cache-Schlüssel aus Endpunkt und Parametern.
+// Zugangsdaten wie das apitoken oder der passhash sind bewusst nicht Teil des Schlüssels.
+func buildCacheKey(endpoint string, params url.Values) string {
+	keyParams := make(url.Values, len(params))
+	for key, values := range params {
+		if credentialParams[key] {
+			continue
+		}
+		keyParams[key] = values
+	}
+	// Encode sortiert die Parameter nach Namen, der Schlüssel ist damit eindeutig.
+	return endpoint + "?" + keyParams.Encode()
+}
+
 // buildApiUrl erstellt eine standardisierte PRTG-API-URL mit übergebenen Parametern.
-func (a *Api) buildApiUrl(method string, params map[string]string) (string, error) {
+func (a *Api) buildApiUrl(method string, params url.Values) (string, error) {
 	baseUrl := fmt.Sprintf("%s/api/%s", a.baseURL, method)
 	u, err := url.Parse(baseUrl)
 	if err != nil {
@@ -40,39 +119,63 @@ func (a *Api) buildApiUrl(method string, params map[string]string) (string, erro
 	}
 
 	q := url.Values{}
-	q.Set("apitoken", a.apiKey)
+	a.auth.apply(q)
 
-	for key, value := range params {
-		q.Set(key, value)
+	for key, values := range params {
+		q[key] = values
 	}
 
 	u.RawQuery = q.Encode()
 	return u.String(), nil
 }
 
-// SetTimeout aktualisiert das Timeout für API-Anfragen.
-func (a *Api) SetTimeout(timeout time.Duration) {
-	if timeout > 0 {
-		a.timeout = timeout
-	}
+// SetRetryPolicy legt fest, wie oft fehlgeschlagene Anfragen wiederholt werden.
+func (a *Api) SetRetryPolicy(policy RetryPolicy) {
+	a.retry = policy
+}
+
+// SetRateLimit begrenzt die Anfragen an PRTG auf requestsPerSecond mit der angegebenen
+// Burst-Größe und höchstens maxInFlight gleichzeitige Anfragen. 0 verwendet die Standardwerte,
+// negative Werte heben die jeweilige Grenze auf.
+func (a *Api) SetRateLimit(requestsPerSecond float64, burst, maxInFlight int) {
+	a.limiter = newRequestLimiter(requestsPerSecond, burst, maxInFlight)
+}
+
+// baseExecuteRequest liefert den Response-Body aus dem Cache oder führt die HTTP-Anfrage durch.
+// Gleichzeitige identische Anfragen teilen sich eine HTTP-Anfrage.
+func (a *Api) baseExecuteRequest(ctx context.Context, endpoint string, params url.Values) ([]byte, error) {
+	cacheable := cacheableEndpoints[endpoint]
+	cacheKey := buildCacheKey(endpoint, params)
+	if cacheable {
+		if body, ok := a.cache.Get(cacheKey); ok {
+			backend.Logger.Debug("Cache hit", "endpoint", endpoint, "key", cacheKey)
+			observeCacheLookup(endpoint, true)
+			return body, nil
+		}
+		backend.Logger.Debug("Cache miss", "endpoint", endpoint, "key", cacheKey)
+		observeCacheLookup(endpoint, false)
+	}
+
+	return shareResult(ctx, &a.requests, endpoint, cacheKey, func(ctx context.Context) ([]byte, error) {
+		body, err := a.doRequestWithRetry(ctx, endpoint, params)
+		if err != nil {
+			return nil, err
+		}
+		if cacheable {
+			a.cache.Set(cacheKey, body, len(body))
+		}
+		return body, nil
+	})
 }
 
-// baseExecuteRequest führt die HTTP-Anfrage durch und liefert den Response-Body.
-func (a *Api) baseExecuteRequest(endpoint string, params map[string]string) ([]byte, error) {
+// doRequest führt die HTTP-Anfrage durch und liefert den Response-Body.
+func (a *Api) doRequest(ctx context.Context, endpoint string, params url.Values) ([]byte, error) {
 	apiUrl, err := a.buildApiUrl(endpoint, params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build URL: %w", err)
 	}
 
-	client := &http.Client{
-		Timeout: a.timeout,
-		Transport: &http.Transport{
-			// Achtung: InsecureSkipVerify sollte in Produktionsumgebungen überprüft werden!
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
-	}
-
-	req, err := http.NewRequest("GET", apiUrl, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiUrl, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -80,133 +183,271 @@ func (a *Api) baseExecuteRequest(endpoint string, params map[string]string) ([]b
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
 
-	resp, err := client.Do(req)
+	// Jede Anfrage, auch jede Wiederholung, wartet auf das Rate-Limit und einen freien Platz.
+	release, err := a.limiter.acquire(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		kind := transportErrorKind(err)
+		if ctx.Err() != nil {
+			kind = transportErrorKind(context.Cause(ctx))
+		} else if kind == nil {
+			// Das Rate-Limit würde die Deadline des Kontexts überschreiten.
+			kind = ErrTimeout
+		}
+		return nil, &APIError{Endpoint: endpoint, Kind: kind, Err: err}
+	}
+	defer release()
+
+	start := time.Now()
+	resp, err := a.client.Do(req)
+	if err != nil {
+		observeRequest(endpoint, 0, time.Since(start), 0)
+		// Die URL enthält die Zugangsdaten und wird aus der Fehlermeldung entfernt.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = a.baseURL + "/api/" + endpoint
+		}
+		// Abgebrochene oder abgelaufene Anfragen werden mit dem Kontextfehler gemeldet;
+		// die Ursache unterscheidet bei geteilten Anfragen Abbruch und Zeitüberschreitung.
+		if ctx.Err() != nil {
+			ctxErr := context.Cause(ctx)
+			return nil, &APIError{Endpoint: endpoint, Kind: transportErrorKind(ctxErr), Err: fmt.Errorf("request aborted: %w", ctxErr)}
+		}
+		return nil, &APIError{Endpoint: endpoint, Kind: transportErrorKind(err), Err: err}
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusForbidden {
-		log.DefaultLogger.Error("Access denied: please verify API token and permissions")
-		return nil, fmt.Errorf("access denied: please verify API token and permissions")
-	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		observeRequest(endpoint, resp.StatusCode, time.Since(start), 0)
+		apiErr := &APIError{Endpoint: endpoint, StatusCode: resp.StatusCode, Kind: statusCodeKind(resp.StatusCode)}
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			log.DefaultLogger.Error("Access denied: please verify credentials and permissions", "authMode", a.auth.Mode)
+			apiErr.Err = fmt.Errorf("access denied (auth mode %s): please verify credentials and permissions", a.auth.Mode)
+		} else if message := prtgErrorMessage(resp.Body); message != "" {
+			apiErr.Err = errors.New(message)
+		}
+		return nil, apiErr
 	}
 
 	body, err := io.ReadAll(resp.Body)
+	observeRequest(endpoint, resp.StatusCode, time.Since(start), len(body))
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, &APIError{Endpoint: endpoint, Kind: transportErrorKind(err), Err: fmt.Errorf("failed to read response body: %w", err)}
 	}
 	return body, nil
 }
 
+// prtgErrorMessage liest die Fehlermeldung, die PRTG bei fehlgeschlagenen Anfragen als JSON liefert.
+func prtgErrorMessage(body io.Reader) string {
+	var response struct {
+		Error string `json:"error"`
+	}
+	data, err := io.ReadAll(io.LimitReader(body, 64<<10))
+	if err != nil || json.Unmarshal(data, &response) != nil {
+		return ""
+	}
+	return response.Error
+}
+
 // GetStatusList ruft die Statusliste der PRTG-API ab.
-func (a *Api) GetStatusList() (*PrtgStatusListResponse, error) {
-	body, err := a.baseExecuteRequest("status.json", nil)
+func (a *Api) GetStatusList(ctx context.Context) (*PrtgStatusListResponse, error) {
+	body, err := a.baseExecuteRequest(ctx, "status.json", nil)
 	if err != nil {
 		return nil, err
 	}
 
 	var response PrtgStatusListResponse
 	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+		return nil, malformedResponse("status.json", err)
 	}
 	return &response, nil
 }
 
-// GetGroups ruft die Gruppenliste ab.
-func (a *Api) GetGroups() (*PrtgGroupListResponse, error) {
-	params := map[string]string{
-		"content": "groups",
-		"columns": "active,channel,datetime,device,group,message,objid,priority,sensor,status,tags",
-		"count":   "50000",
+// objectParams erstellt die Parameter für Gruppen-, Geräte- und Sensortabellen.
+func objectParams(filter *TableFilter) url.Values {
+	params := url.Values{
+		"columns": {objectColumns},
 	}
+	filter.apply(params)
+	return params
+}
 
-	body, err := a.baseExecuteRequest("table.json", params)
+// GetGroups ruft die Gruppenliste seitenweise ab. filter schränkt die Zeilen serverseitig ein und darf nil sein.
+// Gleichzeitige Aufrufe mit demselben Filter teilen sich das Ergebnis.
+func (a *Api) GetGroups(ctx context.Context, filter *TableFilter) (*PrtgGroupListResponse, error) {
+	params := objectParams(filter)
+	return shareResult(ctx, &a.results, "table.json", "groups?"+params.Encode(), func(ctx context.Context) (*PrtgGroupListResponse, error) {
+		rows, treeSize, version, err := collectTable[PrtgGroupListItemStruct](ctx, a, "groups", params)
+		if err != nil {
+			return nil, err
+		}
+
+		return &PrtgGroupListResponse{PrtgVersion: version, TreeSize: treeSize, Groups: rows}, nil
+	})
+}
+
+// GetGroupsPage ruft count Zeilen ab Zeile start ab. TreeSize enthält die Gesamtzahl der passenden Zeilen.
+func (a *Api) GetGroupsPage(ctx context.Context, filter *TableFilter, start, count int) (*PrtgGroupListResponse, error) {
+	rows, treeSize, version, err := fetchTablePage[PrtgGroupListItemStruct](ctx, a, "groups", objectParams(filter), start, count)
 	if err != nil {
 		return nil, err
 	}
 
-	var response PrtgGroupListResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+	return &PrtgGroupListResponse{PrtgVersion: version, TreeSize: treeSize, Groups: rows}, nil
+}
+
+// GetDevices ruft die Geräte-Liste seitenweise ab. filter schränkt die Zeilen serverseitig ein und darf nil sein.
+// Gleichzeitige Aufrufe mit demselben Filter teilen sich das Ergebnis.
+func (a *Api) GetDevices(ctx context.Context, filter *TableFilter) (*PrtgDevicesListResponse, error) {
+	params := objectParams(filter)
+	return shareResult(ctx, &a.results, "table.json", "devices?"+params.Encode(), func(ctx context.Context) (*PrtgDevicesListResponse, error) {
+		rows, treeSize, version, err := collectTable[PrtgDeviceListItemStruct](ctx, a, "devices", params)
+		if err != nil {
+			return nil, err
+		}
+
+		return &PrtgDevicesListResponse{PrtgVersion: version, TreeSize: treeSize, Devices: rows}, nil
+	})
+}
+
+// GetDevicesPage ruft count Zeilen ab Zeile start ab. TreeSize enthält die Gesamtzahl der passenden Zeilen.
+func (a *Api) GetDevicesPage(ctx context.Context, filter *TableFilter, start, count int) (*PrtgDevicesListResponse, error) {
+	rows, treeSize, version, err := fetchTablePage[PrtgDeviceListItemStruct](ctx, a, "devices", objectParams(filter), start, count)
+	if err != nil {
+		return nil, err
 	}
 
-	return &response, nil
+	return &PrtgDevicesListResponse{PrtgVersion: version, TreeSize: treeSize, Devices: rows}, nil
+}
+
+// GetSensors ruft die Sensoren-Liste seitenweise ab. filter schränkt die Zeilen serverseitig ein und darf nil sein.
+// Gleichzeitige Aufrufe mit demselben Filter teilen sich das Ergebnis.
+func (a *Api) GetSensors(ctx context.Context, filter *TableFilter) (*PrtgSensorsListResponse, error) {
+	params := objectParams(filter)
+	return shareResult(ctx, &a.results, "table.json", "sensors?"+params.Encode(), func(ctx context.Context) (*PrtgSensorsListResponse, error) {
+		rows, treeSize, version, err := collectTable[PrtgSensorListItemStruct](ctx, a, "sensors", params)
+		if err != nil {
+			return nil, err
+		}
+
+		return &PrtgSensorsListResponse{PrtgVersion: version, TreeSize: treeSize, Sensors: rows}, nil
+	})
 }
 
-// GetDevices ruft die Geräte-Liste ab.
-func (a *Api) GetDevices() (*PrtgDevicesListResponse, error) {
-	params := map[string]string{
-		"content": "devices",
-		"columns": "active,channel,datetime,device,group,message,objid,priority,sensor,status,tags",
-		"count":   "50000",
+// GetSensorsPage ruft count Zeilen ab Zeile start ab. TreeSize enthält die Gesamtzahl der passenden Zeilen.
+func (a *Api) GetSensorsPage(ctx context.Context, filter *TableFilter, start, count int) (*PrtgSensorsListResponse, error) {
+	rows, treeSize, version, err := fetchTablePage[PrtgSensorListItemStruct](ctx, a, "sensors", objectParams(filter), start, count)
+	if err != nil {
+		return nil, err
 	}
 
-	body, err := a.baseExecuteRequest("table.json", params)
+	return &PrtgSensorsListResponse{PrtgVersion: version, TreeSize: treeSize, Sensors: rows}, nil
+}
+
+// GetChannels ruft die Channel-Werte für die angegebene objid ab.
+func (a *Api) GetChannels(ctx context.Context, objid string) (*PrtgChannelValueStruct, error) {
+	params := url.Values{
+		"content":    {"values"},
+		"id":         {objid},
+		"columns":    {"value_,datetime"},
+		"usecaption": {"true"},
+		"count":      {"50000"},
+	}
+
+	body, err := a.baseExecuteRequest(ctx, "historicdata.json", params)
 	if err != nil {
 		return nil, err
 	}
 
-	var response PrtgDevicesListResponse
+	var response PrtgChannelValueStruct
 	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+		return nil, malformedResponse("historicdata.json", err)
 	}
 
 	return &response, nil
 }
 
-// GetSensors ruft die Sensoren-Liste ab.
-func (a *Api) GetSensors() (*PrtgSensorsListResponse, error) {
-	params := map[string]string{
-		"content": "sensors",
-		"columns": "active,channel,datetime,device,group,message,objid,priority,sensor,status,tags",
-		"count":   "50000",
+// GetChannelMetadata ruft die Kanäle des angegebenen Sensors samt formatiertem letzten Wert ab.
+func (a *Api) GetChannelMetadata(ctx context.Context, sensorID string) (*PrtgChannelMetaListResponse, error) {
+	params := url.Values{
+		"content": {"channels"},
+		"id":      {sensorID},
+		"columns": {"objid,name,lastvalue,lastvalue_raw"},
+		"count":   {"50000"},
 	}
 
-	body, err := a.baseExecuteRequest("table.json", params)
+	body, err := a.baseExecuteRequest(ctx, "table.json", params)
 	if err != nil {
 		return nil, err
 	}
 
-	var response PrtgSensorsListResponse
+	var response PrtgChannelMetaListResponse
 	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+		return nil, malformedResponse("table.json", err)
 	}
 
 	return &response, nil
 }
 
-// GetChannels ruft die Channel-Werte für die angegebene objid ab.
-func (a *Api) GetChannels(objid string) (*PrtgChannelValueStruct, error) {
-	params := map[string]string{
-		"content":    "values",
-		"id":         objid,
-		"columns":    "value_,datetime",
-		"usecaption": "true",
-		"count":      "50000",
+// GetChannelProperty liest eine Eigenschaft des Kanals channelID des angegebenen Sensors,
+// z. B. "limitmaxerror". Nicht gesetzte Eigenschaften liefern einen leeren Wert.
+func (a *Api) GetChannelProperty(ctx context.Context, sensorID string, channelID int64, name string) (string, error) {
+	params := url.Values{
+		"id":      {sensorID},
+		"subtype": {"channel"},
+		"subid":   {strconv.FormatInt(channelID, 10)},
+		"name":    {name},
+		"show":    {"nohtmlencode"},
 	}
 
-	body, err := a.baseExecuteRequest("historicdata.json", params)
+	body, err := a.baseExecuteRequest(ctx, "getobjectproperty.htm", params)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	if err := os.WriteFile("channel_response.txt", body, 0644); err != nil {
-		backend.Logger.Warn("Could not save channel response to file", "error", err)
+	var response PrtgObjectPropertyResponse
+	if err := xml.Unmarshal(body, &response); err != nil {
+		return "", malformedResponse("getobjectproperty.htm", err)
 	}
+	// PRTG meldet unbekannte Eigenschaften im Ergebnis statt mit einem Fehlerstatus.
+	if response.Result == "(Property not found)" {
+		return "", nil
+	}
+	return response.Result, nil
+}
 
-	var response PrtgChannelValueStruct
+// GetMessages ruft die Log-Meldungen des angegebenen Objekts im Zeitraum ab.
+// Ohne objid werden die Meldungen aller Objekte geliefert.
+func (a *Api) GetMessages(ctx context.Context, objid string, startDate, endDate int64) (*PrtgMessagesListResponse, error) {
+	const format = "2006-01-02-15-04-05"
+	params := url.Values{
+		"content":       {"messages"},
+		"columns":       {"objid,datetime,parent,type,name,status,message"},
+		"filter_dstart": {time.UnixMilli(startDate).Format(format)},
+		"filter_dend":   {time.UnixMilli(endDate).Format(format)},
+		"count":         {"50000"},
+	}
+	if objid != "" {
+		params.Set("id", objid)
+	}
+
+	body, err := a.baseExecuteRequest(ctx, "table.json", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var response PrtgMessagesListResponse
 	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+		return nil, malformedResponse("table.json", err)
 	}
 
 	return &response, nil
 }
 
 // GetHistoricalData ruft historische Daten für den angegebenen Sensor und Zeitraum ab.
-func (a *Api) GetHistoricalData(sensorID string, startDate, endDate int64) (*PrtgHistoricalDataResponse, error) {
+// avg ist das Mittelungsintervall in Sekunden, 0 liefert die Rohdaten.
+// Lange Zeiträume werden in Teilabfragen aufgeteilt, parallel abgerufen und zusammengeführt.
+// Gleichzeitige Aufrufe für denselben Sensor und Zeitraum teilen sich das Ergebnis.
+func (a *Api) GetHistoricalData(ctx context.Context, sensorID string, startDate, endDate int64, avg int64) (*PrtgHistoricalDataResponse, error) {
 
 	// Input validation
 	if sensorID == "" {
@@ -217,65 +458,95 @@ func (a *Api) GetHistoricalData(sensorID string, startDate, endDate int64) (*Prt
 	startTime := time.UnixMilli(startDate)
 	endTime := time.UnixMilli(endDate)
 
-	// Format dates
-	const format = "2006-01-02-15-04-05"
-	sdate := startTime.Format(format)
-	edate := endTime.Format(format)
-
-	// Calculate hours and validate time range
-	hours := endTime.Sub(startTime).Hours()
-	if hours <= 0 {
+	// Validate time range
+	if !endTime.After(startTime) {
 		return nil, fmt.Errorf("invalid time range: start date %v must be before end date %v", startTime, endTime)
 	}
 
-	// Determine averaging interval
-	var avg string
-	switch {
-	case hours <= 12:
-		avg = "0"
-	case hours <= 36:
-		avg = "60"
-	case hours <= 72:
-		avg = "300"
-	case hours <= 168:
-		avg = "900"
-	case hours <= 336:
-		avg = "1800"
-	case hours <= 720:
-		avg = "3600"
-	case hours <= 1440:
-		avg = "7200"
-	case hours <= 2160:
-		avg = "14400"
-	default:
-		avg = "86400"
+	key := fmt.Sprintf("%s|%d|%d|%d", sensorID, startDate, endDate, avg)
+	return shareResult(ctx, &a.results, "historicdata.json", key, func(ctx context.Context) (*PrtgHistoricalDataResponse, error) {
+		return a.fetchHistoricalData(ctx, sensorID, startTime, endTime, avg)
+	})
+}
+
+// fetchHistoricalData teilt den Zeitraum in Teilabfragen auf, ruft sie parallel ab und führt sie zusammen.
+func (a *Api) fetchHistoricalData(ctx context.Context, sensorID string, startTime, endTime time.Time, avg int64) (*PrtgHistoricalDataResponse, error) {
+	chunks := historicChunks(startTime, endTime, avg)
+	parts := make([]*PrtgHistoricalDataResponse, len(chunks))
+	errs := make([]error, len(chunks))
+
+	// Bei einem Fehler werden die übrigen Teilabfragen abgebrochen.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sem := make(chan struct{}, historicChunkConcurrency)
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(i int, chunk historicChunk) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+			parts[i], errs[i] = a.getHistoricalChunk(ctx, sensorID, chunk, avg)
+			if errs[i] != nil {
+				cancel()
+			}
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	// Den ursprünglichen Fehler statt der Folgeabbrüche melden.
+	var firstErr error
+	for _, err := range errs {
+		if err != nil && (firstErr == nil || errors.Is(firstErr, context.Canceled)) {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return nil, fmt.Errorf("failed to fetch historical data: %w", firstErr)
+	}
+
+	response := mergeHistoricChunks(parts)
+
+	// Validate response
+	if len(response.HistData) == 0 {
+		return nil, &APIError{Endpoint: "historicdata.json", Kind: ErrNotFound, Err: errors.New("no data found for the given time range")}
 	}
+
+	return response, nil
+}
+
+// getHistoricalChunk ruft die historischen Daten eines Teilzeitraums mit einer einzelnen Anfrage ab.
+func (a *Api) getHistoricalChunk(ctx context.Context, sensorID string, chunk historicChunk, avg int64) (*PrtgHistoricalDataResponse, error) {
+	// Format dates
+	const format = "2006-01-02-15-04-05"
+
 	// Set up API request parameters
-	params := map[string]string{
-		"id":         sensorID,
-		"columns":    "datetime,value_",
-		"avg":        avg,
-		"sdate":      sdate,
-		"edate":      edate,
-		"count":      "50000",
-		"usecaption": "1",
+	params := url.Values{
+		"id":         {sensorID},
+		"columns":    {"datetime,value_"},
+		"avg":        {strconv.FormatInt(avg, 10)},
+		"sdate":      {chunk.start.Format(format)},
+		"edate":      {chunk.end.Format(format)},
+		"count":      {strconv.Itoa(historicMaxRows)},
+		"usecaption": {"1"},
 	}
 
 	// Make API request
-	body, err := a.baseExecuteRequest("historicdata.json", params)
+	body, err := a.baseExecuteRequest(ctx, "historicdata.json", params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch historical data: %w", err)
+		return nil, err
 	}
 
 	// Parse response
 	var response PrtgHistoricalDataResponse
 	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	// Validate response
-	if len(response.HistData) == 0 {
-		return nil, fmt.Errorf("no data found for the given time range")
+		return nil, malformedResponse("historicdata.json", err)
 	}
 
 	return &response, nil
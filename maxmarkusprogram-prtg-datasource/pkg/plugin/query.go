@@ -17,19 +17,17 @@ import (
 
 // PRTGAPI defines the interface for API operations.
 type PRTGAPI interface {
-	GetGroups() (*PrtgGroupListResponse, error)
-	GetDevices() (*PrtgDevicesListResponse, error)
-	GetSensors() (*PrtgSensorsListResponse, error)
+	GetGroups(ctx context.Context, filter *TableFilter) (*PrtgGroupListResponse, error)
+	GetDevices(ctx context.Context, filter *TableFilter) (*PrtgDevicesListResponse, error)
+	GetSensors(ctx context.Context, filter *TableFilter) (*PrtgSensorsListResponse, error)
 	// Additional methods like GetTextData, GetPropertyData, etc. can be declared here.
 }
 
 // query processes a single query. If QueryType is "metrics", it creates a time series,
 // otherwise property-based queries are handled by handlePropertyQuery.
 func (d *Datasource) query(ctx context.Context, pCtx backend.PluginContext, query backend.DataQuery) backend.DataResponse {
-	_ = ctx  // ! Unused parameter: ctx is intentionally not used.
 	_ = pCtx // ! Unused parameter: pCtx is intentionally not used.
 
-	var response backend.DataResponse
 	var qm queryModel
 
 	if err := json.Unmarshal(query.JSON, &qm); err != nil {
@@ -38,74 +36,17 @@ func (d *Datasource) query(ctx context.Context, pCtx backend.PluginContext, quer
 
 	switch qm.QueryType {
 	case "metrics":
-		// Metrics handling code
-		fromTime := query.TimeRange.From.UnixMilli()
-		toTime := query.TimeRange.To.UnixMilli()
-
-		historicalData, err := d.api.GetHistoricalData(qm.ObjectId, fromTime, toTime)
-		if err != nil {
-			backend.Logger.Error("API request failed", "error", err)
-			return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("API request failed: %v", err))
-		}
-
-		// Assumption: historicalData.Treesize contains the value from the JSON ("treesize")
-		times := make([]time.Time, 0)
-		values := make([]float64, 0)
-
-		for _, item := range historicalData.HistData {
-			parsedTime, _, err := parsePRTGDateTime(item.Datetime)
-			if err != nil {
-				backend.Logger.Warn("Date parsing failed", "datetime", item.Datetime, "error", err)
-				continue
-			}
-			if val, ok := item.Value[qm.Channel]; ok {
-				switch v := val.(type) {
-				case float64:
-					values = append(values, v)
-				case string:
-					if floatVal, err := strconv.ParseFloat(v, 64); err == nil {
-						values = append(values, floatVal)
-					} else {
-						backend.Logger.Warn("Cannot convert value to float64", "value", v, "error", err)
-						continue
-					}
-				default:
-					backend.Logger.Warn("Unexpected value type", "type", fmt.Sprintf("%T", v), "value", v)
-					continue
-				}
-				times = append(times, parsedTime)
-			} else {
-				backend.Logger.Warn("Channel not found in item.Value, using default value", "channel", qm.Channel)
-				times = append(times, parsedTime)
-				values = append(values, 0.0)
-			}
-		}
-
-		var parts []string
-		if qm.IncludeGroupName && qm.Group != "" {
-			parts = append(parts, qm.Group)
-		}
-		if qm.IncludeDeviceName && qm.Device != "" {
-			parts = append(parts, qm.Device)
-		}
-		if qm.IncludeSensorName && qm.Sensor != "" {
-			parts = append(parts, qm.Sensor)
-		}
-		parts = append(parts, qm.Channel)
-		displayName := strings.Join(parts, " - ")
+		return d.handleMetricsQuery(ctx, qm, query)
 
-		frame := data.NewFrame("response",
-			data.NewField("Time", nil, times),
-			data.NewField("Value", nil, values).SetConfig(&data.FieldConfig{
-				DisplayName: displayName,
-			}),
-		)
+	case "variable":
+		return d.handleVariableQuery(ctx, qm)
 
-		response.Frames = append(response.Frames, frame)
+	case "annotations":
+		return d.handleAnnotationsQuery(ctx, qm, query)
 
 	case "text":
 		// Handle text mode by using the non-raw property
-		return d.handlePropertyQuery(qm, qm.FilterProperty)
+		return d.handlePropertyQuery(ctx, qm, qm.FilterProperty)
 
 	case "raw":
 		// Handle raw mode by appending "_raw" to the filter property
@@ -113,34 +54,36 @@ func (d *Datasource) query(ctx context.Context, pCtx backend.PluginContext, quer
 		if !strings.HasSuffix(rawProperty, "_raw") {
 			rawProperty += "_raw"
 		}
-		return d.handlePropertyQuery(qm, rawProperty)
+		return d.handlePropertyQuery(ctx, qm, rawProperty)
 
 	default:
 		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("Unknown query type: %s", qm.QueryType))
 	}
-
-	return response
 }
 
 // handlePropertyQuery processes a property query based on the queryModel (qm)
 // and a filter property.
-func (d *Datasource) handlePropertyQuery(qm queryModel, filterProperty string) backend.DataResponse {
+func (d *Datasource) handlePropertyQuery(ctx context.Context, qm queryModel, filterProperty string) backend.DataResponse {
 	var response backend.DataResponse
-	var times []time.Time
-	var values []interface{}
+	series := &propertySeries{}
 
 	if !d.isValidPropertyType(qm.Property) {
 		return backend.ErrDataResponse(backend.StatusBadRequest, "Invalid property type")
 	}
 
+	tagFilter, err := parseTagFilter(qm.TagFilter)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+
 	switch qm.Property {
 	case "group":
-		groups, err := d.api.GetGroups()
+		groups, err := d.api.GetGroups(ctx, objectFilter("group", qm.Group, tagFilter))
 		if err != nil {
-			return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("API request failed: %v", err))
+			return apiErrorResponse("API request failed", err)
 		}
 		for _, g := range groups.Groups {
-			if g.Group == qm.Group {
+			if matchPropertyObject(g.Group, qm.Group, g.Tags, tagFilter) {
 				timestamp, _, err := parsePRTGDateTime(g.Datetime)
 				if err != nil {
 					backend.Logger.Warn("Date parsing failed", "datetime", g.Datetime, "error", err)
@@ -173,20 +116,19 @@ func (d *Datasource) handlePropertyQuery(qm queryModel, filterProperty string) b
 				}
 
 				if value != nil {
-					times = append(times, timestamp)
-					values = append(values, value)
+					series.add(propertyObject{objid: g.ObjectId, group: g.Group}, timestamp, value)
 				}
 			}
 		}
 
 	case "device":
 		// Similar structure for devices
-		devices, err := d.api.GetDevices()
+		devices, err := d.api.GetDevices(ctx, objectFilter("device", qm.Device, tagFilter))
 		if err != nil {
-			return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("API request failed: %v", err))
+			return apiErrorResponse("API request failed", err)
 		}
 		for _, dev := range devices.Devices {
-			if dev.Device == qm.Device {
+			if matchPropertyObject(dev.Device, qm.Device, dev.Tags, tagFilter) {
 				timestamp, _, err := parsePRTGDateTime(dev.Datetime)
 				if err != nil {
 					continue
@@ -217,19 +159,18 @@ func (d *Datasource) handlePropertyQuery(qm queryModel, filterProperty string) b
 				}
 
 				if value != nil {
-					times = append(times, timestamp)
-					values = append(values, value)
+					series.add(propertyObject{objid: dev.ObjectId, group: dev.Group, device: dev.Device}, timestamp, value)
 				}
 			}
 		}
 
 	case "sensor":
-		sensors, err := d.api.GetSensors()
+		sensors, err := d.api.GetSensors(ctx, objectFilter("sensor", qm.Sensor, tagFilter))
 		if err != nil {
-			return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("API request failed: %v", err))
+			return apiErrorResponse("API request failed", err)
 		}
 		for _, s := range sensors.Sensors {
-			if s.Sensor == qm.Sensor {
+			if matchPropertyObject(s.Sensor, qm.Sensor, s.Tags, tagFilter) {
 				timestamp, _, err := parsePRTGDateTime(s.Datetime)
 				if err != nil {
 					backend.Logger.Error("Failed to parse sensor datetime",
@@ -275,65 +216,151 @@ func (d *Datasource) handlePropertyQuery(qm queryModel, filterProperty string) b
 				}
 
 				if value != nil {
-					times = append(times, timestamp)
-					values = append(values, value)
+					series.add(propertyObject{objid: s.ObjectId, group: s.Group, device: s.Device, sensor: s.Sensor}, timestamp, value)
 				}
 			}
 		}
 	}
 
-	// Create a frame with proper field configuration
-	if len(times) > 0 && len(values) > 0 {
-		timeField := data.NewField("Time", nil, times)
-
-		// Determine the type of values and create an appropriate field
-		var valueField *data.Field
-		if len(values) > 0 {
-			switch values[0].(type) {
-			case float64, int:
-				// Convert all values to float64
-				floatVals := make([]float64, len(values))
-				for i, v := range values {
-					switch tv := v.(type) {
-					case float64:
-						floatVals[i] = tv
-					case int:
-						floatVals[i] = float64(tv)
-					}
-				}
-				valueField = data.NewField("Value", nil, floatVals)
-			case string:
-				// Keep string values as they are
-				strVals := make([]string, len(values))
-				for i, v := range values {
-					strVals[i] = v.(string)
-				}
-				valueField = data.NewField("Value", nil, strVals)
-			default:
-				// Convert other types to strings
-				strVals := make([]string, len(values))
-				for i, v := range values {
-					strVals[i] = fmt.Sprintf("%v", v)
-				}
-				valueField = data.NewField("Value", nil, strVals)
-			}
+	// Create one frame per matching object; without a tag filter this is the selected object.
+	// Objects matched by a tag filter may share their name, so their parent is shown as well.
+	for _, id := range series.order {
+		object := series.objects[id]
+		name := object.name(qm.Property)
+		if tagFilter != nil {
+			name = object.qualifiedName(qm.Property)
 		}
+		displayName := fmt.Sprintf("%s - %s (%s)", qm.Property, name, filterProperty)
+		response.Frames = append(response.Frames, buildPropertyFrame(displayName, object.labels(), series.times[id], series.values[id]))
+	}
 
-		// Set display name
-		displayName := fmt.Sprintf("%s - %s (%s)", qm.Property, qm.Sensor, filterProperty)
-		valueField.Config = &data.FieldConfig{
-			DisplayName: displayName,
-		}
+	return response
+}
+
+// propertyObject identifies the group, device or sensor a property value belongs to.
+type propertyObject struct {
+	objid  int64
+	group  string
+	device string
+	sensor string
+}
 
-		frame := data.NewFrame("response",
-			timeField,
-			valueField,
-		)
+// name returns the name of the object of the given property type.
+func (o propertyObject) name(property string) string {
+	switch property {
+	case "group":
+		return o.group
+	case "device":
+		return o.device
+	}
+	return o.sensor
+}
 
-		response.Frames = append(response.Frames, frame)
+// qualifiedName prefixes the object name with its device or group, or appends the objid
+// for groups, whose rows carry no parent name.
+func (o propertyObject) qualifiedName(property string) string {
+	switch property {
+	case "group":
+		return fmt.Sprintf("%s [%d]", o.group, o.objid)
+	case "device":
+		return o.group + " / " + o.device
 	}
+	return o.device + " / " + o.sensor
+}
 
-	return response
+// labels returns the labels identifying the object, like metricLabels for channels.
+func (o propertyObject) labels() data.Labels {
+	labels := data.Labels{"objid": strconv.FormatInt(o.objid, 10)}
+	if o.group != "" {
+		labels["group"] = o.group
+	}
+	if o.device != "" {
+		labels["device"] = o.device
+	}
+	if o.sensor != "" {
+		labels["sensor"] = o.sensor
+	}
+	return labels
+}
+
+// propertySeries collects property values per object in the order the objects were seen.
+// Objects are keyed by objid, so objects of the same name stay separate series.
+type propertySeries struct {
+	order   []int64
+	objects map[int64]propertyObject
+	times   map[int64][]time.Time
+	values  map[int64][]interface{}
+}
+
+// add appends a value of the object.
+func (p *propertySeries) add(object propertyObject, timestamp time.Time, value interface{}) {
+	if p.objects == nil {
+		p.objects = make(map[int64]propertyObject)
+		p.times = make(map[int64][]time.Time)
+		p.values = make(map[int64][]interface{})
+	}
+	if _, ok := p.objects[object.objid]; !ok {
+		p.order = append(p.order, object.objid)
+		p.objects[object.objid] = object
+	}
+	p.times[object.objid] = append(p.times[object.objid], timestamp)
+	p.values[object.objid] = append(p.values[object.objid], value)
+}
+
+// matchPropertyObject reports whether an object belongs to a property query. Without a tag
+// filter the object name must equal the selected name; with a tag filter the tags must match
+// and the name is only compared when one was selected.
+func matchPropertyObject(name, selected, tags string, tagFilter tagExpr) bool {
+	if tagFilter == nil {
+		return name == selected
+	}
+	return matchTags(tagFilter, tags) && (selected == "" || name == selected)
+}
+
+// buildPropertyFrame creates a frame with proper field configuration for property values.
+func buildPropertyFrame(displayName string, labels data.Labels, times []time.Time, values []interface{}) *data.Frame {
+	timeField := data.NewField("Time", nil, times)
+
+	// Determine the type of values and create an appropriate field
+	var valueField *data.Field
+	switch values[0].(type) {
+	case float64, int:
+		// Convert all values to float64
+		floatVals := make([]float64, len(values))
+		for i, v := range values {
+			switch tv := v.(type) {
+			case float64:
+				floatVals[i] = tv
+			case int:
+				floatVals[i] = float64(tv)
+			}
+		}
+		valueField = data.NewField("Value", nil, floatVals)
+	case string:
+		// Keep string values as they are
+		strVals := make([]string, len(values))
+		for i, v := range values {
+			strVals[i] = v.(string)
+		}
+		valueField = data.NewField("Value", nil, strVals)
+	default:
+		// Convert other types to strings
+		strVals := make([]string, len(values))
+		for i, v := range values {
+			strVals[i] = fmt.Sprintf("%v", v)
+		}
+		valueField = data.NewField("Value", nil, strVals)
+	}
+
+	valueField.Labels = labels
+	valueField.Config = &data.FieldConfig{
+		DisplayName: displayName,
+	}
+
+	return data.NewFrame("response",
+		timeField,
+		valueField,
+	)
 }
 
 // GetPropertyValue retrieves the property value from an item using reflection.
@@ -0,0 +1,52 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPropertySeriesKeepsSameNamedObjectsApart(t *testing.T) {
+	now := time.Now()
+	var series propertySeries
+	series.add(propertyObject{objid: 1001, group: "Berlin", device: "fw01", sensor: "Ping"}, now, "Up")
+	series.add(propertyObject{objid: 2001, group: "Hamburg", device: "fw02", sensor: "Ping"}, now, "Down")
+	series.add(propertyObject{objid: 1001, group: "Berlin", device: "fw01", sensor: "Ping"}, now.Add(time.Minute), "Up")
+
+	if len(series.order) != 2 {
+		t.Fatalf("got %d series, want 2", len(series.order))
+	}
+	if got := len(series.values[1001]); got != 2 {
+		t.Fatalf("objid 1001 has %d values, want 2", got)
+	}
+	if got := series.values[2001]; len(got) != 1 || got[0] != "Down" {
+		t.Fatalf("objid 2001 values = %v, want [Down]", got)
+	}
+}
+
+func TestPropertyObjectNames(t *testing.T) {
+	object := propertyObject{objid: 2001, group: "Hamburg", device: "fw02", sensor: "Ping"}
+
+	tests := []struct {
+		property  string
+		name      string
+		qualified string
+	}{
+		{property: "group", name: "Hamburg", qualified: "Hamburg [2001]"},
+		{property: "device", name: "fw02", qualified: "Hamburg / fw02"},
+		{property: "sensor", name: "Ping", qualified: "fw02 / Ping"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.property, func(t *testing.T) {
+			if got := object.name(tt.property); got != tt.name {
+				t.Errorf("name() = %q, want %q", got, tt.name)
+			}
+			if got := object.qualifiedName(tt.property); got != tt.qualified {
+				t.Errorf("qualifiedName() = %q, want %q", got, tt.qualified)
+			}
+		})
+	}
+
+	if got := object.labels()["objid"]; got != "2001" {
+		t.Errorf("objid label = %q, want 2001", got)
+	}
+}
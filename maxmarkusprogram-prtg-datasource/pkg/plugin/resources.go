@@ -0,0 +1,180 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+// resourceStatuses maps the status names accepted by the resource endpoints to PRTG states.
+var resourceStatuses = map[string][]int{
+	"unknown":      {prtgStatusUnknown},
+	"collecting":   {prtgStatusCollecting},
+	"up":           {prtgStatusUp},
+	"warning":      {prtgStatusWarning},
+	"down":         {prtgStatusDown, prtgStatusDownAcknowledged, prtgStatusDownPartial},
+	"acknowledged": {prtgStatusDownAcknowledged},
+	"partial":      {prtgStatusDownPartial},
+	"noprobe":      {prtgStatusNoProbe},
+	"paused":       {prtgStatusPausedByUser, prtgStatusPausedByDependency, prtgStatusPausedBySchedule, prtgStatusNotLicensed, prtgStatusPausedUntil},
+	"unusual":      {prtgStatusUnusual},
+}
+
+// resourceObject is the slim representation of a group, device or sensor returned by
+// the resource endpoints.
+type resourceObject struct {
+	Name     string `json:"name"`
+	ObjectId int64  `json:"objid"`
+	Parent   string `json:"parent,omitempty"`
+	ParentId int64  `json:"parentid"`
+	Status   string `json:"status"`
+}
+
+// resourceQuery holds the filter and paging parsed from a resource request.
+type resourceQuery struct {
+	filter *TableFilter
+	offset int
+	limit  int
+}
+
+// paged reports whether the request asks for a single page instead of all rows.
+func (q resourceQuery) paged() bool {
+	return q.offset > 0 || q.limit > 0
+}
+
+// pageSize returns the number of rows to request for a paged query.
+func (q resourceQuery) pageSize() int {
+	if q.limit > 0 {
+		return q.limit
+	}
+	return tablePageSize
+}
+
+// parseResourceQuery parses the query string of /groups, /devices and /sensors:
+//
+//	group   objid or name of the group (objid only for /groups, where it selects subgroups)
+//	device  objid or name of the device (/sensors only)
+//	status  comma-separated status names (up, down, warning, paused, ...) or PRTG status numbers
+//	search  substring of the object name
+//	limit, offset  paging over the matching objects; limit is capped at tablePageSize
+//
+// nameColumn is the table column holding the object name, e.g. "sensor".
+func parseResourceQuery(rawURL, nameColumn string) (resourceQuery, error) {
+	var q resourceQuery
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return q, fmt.Errorf("invalid request URL: %w", err)
+	}
+	params := u.Query()
+	filter := NewTableFilter()
+
+	if group := params.Get("group"); group != "" {
+		_, err := strconv.ParseInt(group, 10, 64)
+		switch {
+		case err == nil && nameColumn == "group":
+			filter.ParentIds(group)
+		case err == nil:
+			filter.Within(group)
+		case nameColumn == "group":
+			return q, fmt.Errorf("invalid group %q: groups can only be filtered by parent objid", group)
+		default:
+			filter.Equals("group", group)
+		}
+	}
+	if device := params.Get("device"); device != "" {
+		if nameColumn != "sensor" {
+			return q, fmt.Errorf("device filter is only supported for sensors")
+		}
+		if _, err := strconv.ParseInt(device, 10, 64); err == nil {
+			filter.ParentIds(device)
+		} else {
+			filter.Equals("device", device)
+		}
+	}
+	for _, value := range params["status"] {
+		for _, status := range strings.Split(value, ",") {
+			status = strings.ToLower(strings.TrimSpace(status))
+			if status == "" {
+				continue
+			}
+			if states, ok := resourceStatuses[status]; ok {
+				filter.Status(states...)
+			} else if state, err := strconv.Atoi(status); err == nil && state > 0 {
+				filter.Status(state)
+			} else {
+				return q, fmt.Errorf("invalid status %q", status)
+			}
+		}
+	}
+	if search := strings.TrimSpace(params.Get("search")); search != "" {
+		filter.Contains(nameColumn, search)
+	}
+
+	if q.limit, err = parseResourceInt(params, "limit"); err != nil {
+		return q, err
+	}
+	// A page of a resource endpoint is fetched with a single table request.
+	if q.limit > tablePageSize {
+		q.limit = tablePageSize
+	}
+	if q.offset, err = parseResourceInt(params, "offset"); err != nil {
+		return q, err
+	}
+
+	q.filter = filter
+	return q, nil
+}
+
+// parseResourceInt parses a non-negative integer parameter; a missing parameter is 0.
+func parseResourceInt(params url.Values, name string) (int, error) {
+	value := params.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, value)
+	}
+	return n, nil
+}
+
+// sendResourceList sends the objects of a resource endpoint as {"total": n, "<key>": [...]}.
+// total is the number of matching objects reported by PRTG, independent of paging.
+func sendResourceList(sender backend.CallResourceResponseSender, key string, total int64, objects []resourceObject) error {
+	if objects == nil {
+		objects = []resourceObject{}
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"total": total,
+		key:     objects,
+	})
+	if err != nil {
+		return sendResourceError(sender, http.StatusInternalServerError, fmt.Sprintf("error marshaling %s: %v", key, err))
+	}
+	return sender.Send(&backend.CallResourceResponse{
+		Status:  http.StatusOK,
+		Headers: map[string][]string{"Content-Type": {"application/json"}},
+		Body:    body,
+	})
+}
+
+// sendResourceError sends {"error": message} with the given HTTP status.
+func sendResourceError(sender backend.CallResourceResponseSender, status int, message string) error {
+	body, _ := json.Marshal(map[string]string{"error": message})
+	return sender.Send(&backend.CallResourceResponse{
+		Status:  status,
+		Headers: map[string][]string{"Content-Type": {"application/json"}},
+		Body:    body,
+	})
+}
+
+// sendAPIError sends a failed API call with the HTTP status matching the error.
+func sendAPIError(sender backend.CallResourceResponseSender, err error) error {
+	status, _ := errorStatus(err)
+	return sendResourceError(sender, int(status), err.Error())
+}
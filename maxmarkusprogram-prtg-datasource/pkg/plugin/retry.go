@@ -0,0 +1,105 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+const (
+	defaultRetryMaxAttempts = 3
+	defaultRetryBaseDelay   = 500 * time.Millisecond
+	defaultRetryMaxDelay    = 10 * time.Second
+)
+
+// RetryPolicy controls how often failed GET requests to PRTG are repeated.
+// Zero values fall back to the defaults; MaxAttempts of 1 disables retries.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// withDefaults returns the policy with unset values replaced by the defaults.
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaultRetryMaxAttempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = defaultRetryBaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = defaultRetryMaxDelay
+	}
+	if p.MaxDelay < p.BaseDelay {
+		p.MaxDelay = p.BaseDelay
+	}
+	return p
+}
+
+// backoff returns the delay before the given retry (1 for the first retry) using
+// exponential backoff with full jitter, so parallel panels do not retry in lockstep.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	ceiling := p.MaxDelay
+	if shift := retry - 1; shift < 30 {
+		if d := p.BaseDelay << shift; d > 0 && d < ceiling {
+			ceiling = d
+		}
+	}
+	return time.Duration(rand.Int64N(int64(ceiling) + 1))
+}
+
+// isRetryable reports whether a failed request may succeed when repeated: PRTG 5xx and
+// 429 responses, timeouts and dropped or refused connections.
+func isRetryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode != 0 {
+		return apiErr.StatusCode >= http.StatusInternalServerError || apiErr.StatusCode == http.StatusTooManyRequests
+	}
+	return errors.Is(err, ErrTimeout) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
+
+// doRequestWithRetry performs the request and repeats it on transient failures. It gives up
+// when the attempts are used up or the next attempt would start after the context deadline.
+func (a *Api) doRequestWithRetry(ctx context.Context, endpoint string, params url.Values) ([]byte, error) {
+	policy := a.retry.withDefaults()
+
+	for attempt := 1; ; attempt++ {
+		body, err := a.doRequest(ctx, endpoint, params)
+		if err == nil {
+			return body, nil
+		}
+		if attempt >= policy.MaxAttempts || ctx.Err() != nil || !isRetryable(err) {
+			observeError(endpoint, err)
+			return nil, err
+		}
+
+		delay := policy.backoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			observeError(endpoint, err)
+			return nil, err
+		}
+		backend.Logger.Warn("Retrying PRTG request", "endpoint", endpoint, "attempt", attempt+1, "delay", delay, "error", err)
+		observeRetry(endpoint)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			observeError(endpoint, err)
+			return nil, err
+		case <-timer.C:
+		}
+	}
+}
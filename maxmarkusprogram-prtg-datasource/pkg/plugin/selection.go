@@ -0,0 +1,295 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// metricTarget is a sensor whose historic data is queried by a metrics query.
+type metricTarget struct {
+	ObjectId string
+	Group    string
+	Device   string
+	Sensor   string
+}
+
+// isMultiObjectQuery reports whether the query selects sensors through the
+// Groups, Devices or Sensors lists, a selector or a tag filter instead of a single objid.
+func (qm queryModel) isMultiObjectQuery() bool {
+	return len(qm.Groups) > 0 || len(qm.Devices) > 0 || len(qm.Sensors) > 0 ||
+		strings.TrimSpace(qm.Selector) != "" || strings.TrimSpace(qm.TagFilter) != ""
+}
+
+// selectionCacheKey identifies the sensor selection of a query in the resolution cache.
+func (qm queryModel) selectionCacheKey() string {
+	return strings.Join([]string{
+		strings.Join(qm.Groups, "\x1f"),
+		strings.Join(qm.Devices, "\x1f"),
+		strings.Join(qm.Sensors, "\x1f"),
+		strings.TrimSpace(qm.Selector),
+		strings.TrimSpace(qm.TagFilter),
+	}, "\x1e")
+}
+
+// resolveTargets returns the sensors selected by the query. Single-object queries
+// use objid directly; multi-object queries are resolved against the sensor list
+// and the result is cached for the configured cache time.
+func (d *Datasource) resolveTargets(ctx context.Context, qm queryModel) ([]metricTarget, error) {
+	if !qm.isMultiObjectQuery() {
+		return []metricTarget{{
+			ObjectId: qm.ObjectId,
+			Group:    qm.Group,
+			Device:   qm.Device,
+			Sensor:   qm.Sensor,
+		}}, nil
+	}
+
+	cacheKey := qm.selectionCacheKey()
+	if targets, ok := d.resolveCache.Get(cacheKey); ok {
+		return targets, nil
+	}
+
+	selection, err := newSensorSelection(qm)
+	if err != nil {
+		return nil, err
+	}
+
+	sensors, err := d.api.GetSensors(ctx, selection.filter())
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []metricTarget
+	size := 0
+	for _, s := range sensors.Sensors {
+		objid := strconv.FormatInt(s.ObjectId, 10)
+		if !selection.matches(s.Group, s.Device, s.Sensor, objid, s.Tags) {
+			continue
+		}
+		targets = append(targets, metricTarget{
+			ObjectId: objid,
+			Group:    s.Group,
+			Device:   s.Device,
+			Sensor:   s.Sensor,
+		})
+		size += len(objid) + len(s.Group) + len(s.Device) + len(s.Sensor)
+	}
+
+	d.resolveCache.Set(cacheKey, targets, size)
+	return targets, nil
+}
+
+// sensorSelection holds the compiled group, device and sensor patterns and the tag filter of a query.
+// An empty list matches everything, otherwise any pattern of the list must match.
+type sensorSelection struct {
+	groups  []*namePattern
+	devices []*namePattern
+	sensors []*namePattern
+	tags    tagExpr
+}
+
+// newSensorSelection compiles the Groups, Devices and Sensors lists and the selector of qm.
+func newSensorSelection(qm queryModel) (*sensorSelection, error) {
+	groups := append([]string(nil), qm.Groups...)
+	devices := append([]string(nil), qm.Devices...)
+	sensors := append([]string(nil), qm.Sensors...)
+
+	terms, err := parseSelector(qm.Selector)
+	if err != nil {
+		return nil, err
+	}
+	for _, term := range terms {
+		switch term.key {
+		case "group":
+			groups = append(groups, term.value)
+		case "device":
+			devices = append(devices, term.value)
+		case "sensor":
+			sensors = append(sensors, term.value)
+		default:
+			return nil, fmt.Errorf("invalid selector key %q: expected group, device or sensor", term.key)
+		}
+	}
+
+	selection := &sensorSelection{}
+	if selection.groups, err = compilePatterns(groups); err != nil {
+		return nil, err
+	}
+	if selection.devices, err = compilePatterns(devices); err != nil {
+		return nil, err
+	}
+	if selection.sensors, err = compilePatterns(sensors); err != nil {
+		return nil, err
+	}
+	if selection.tags, err = parseTagFilter(qm.TagFilter); err != nil {
+		return nil, err
+	}
+	return selection, nil
+}
+
+// filter returns the server-side filter for the selection. The tag filter and exact group
+// and device names are pushed to PRTG; wildcards, regular expressions and sensor names,
+// which may also be objids, are only matched locally.
+func (s *sensorSelection) filter() *TableFilter {
+	filter := objectFilter("sensor", "", s.tags)
+	for _, name := range exactNames(s.groups) {
+		filter.Equals("group", name)
+	}
+	for _, name := range exactNames(s.devices) {
+		filter.Equals("device", name)
+	}
+	return filter
+}
+
+// matches reports whether a sensor is part of the selection.
+// Sensor patterns also accept the exact objid of the sensor.
+func (s *sensorSelection) matches(group, device, sensor, objid, tags string) bool {
+	return matchAny(s.groups, group) &&
+		matchAny(s.devices, device) &&
+		(matchAny(s.sensors, sensor) || matchAny(s.sensors, objid)) &&
+		matchTags(s.tags, tags)
+}
+
+// namePattern matches object names exactly, by wildcard ("*", "?") or by regular expression ("/.../").
+type namePattern struct {
+	exact string
+	re    *regexp.Regexp
+}
+
+// compilePattern parses a single name pattern.
+// Wildcard patterns are matched case-insensitively against the whole name.
+func compilePattern(pattern string) (*namePattern, error) {
+	if len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", pattern, err)
+		}
+		return &namePattern{re: re}, nil
+	}
+
+	if strings.ContainsAny(pattern, "*?") {
+		expr := regexp.QuoteMeta(pattern)
+		expr = strings.ReplaceAll(expr, `\*`, ".*")
+		expr = strings.ReplaceAll(expr, `\?`, ".")
+		return &namePattern{re: regexp.MustCompile("(?i)^" + expr + "$")}, nil
+	}
+
+	return &namePattern{exact: pattern}, nil
+}
+
+// compilePatterns parses a list of name patterns.
+func compilePatterns(patterns []string) ([]*namePattern, error) {
+	compiled := make([]*namePattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		p, err := compilePattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, p)
+	}
+	return compiled, nil
+}
+
+// match reports whether name matches the pattern.
+func (p *namePattern) match(name string) bool {
+	if p.re != nil {
+		return p.re.MatchString(name)
+	}
+	return p.exact == name
+}
+
+// exactNames returns the names of the patterns if all of them are exact. PRTG combines
+// repeated filters of one column with OR, so a single pattern that is not exact would
+// be filtered out; nil is returned in that case.
+func exactNames(patterns []*namePattern) []string {
+	var names []string
+	for _, p := range patterns {
+		if p.re != nil {
+			return nil
+		}
+		names = append(names, p.exact)
+	}
+	return names
+}
+
+// matchAny reports whether name matches one of the patterns. An empty list matches every name.
+func matchAny(patterns []*namePattern, name string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if p.match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectorTerm is a single key=value term of a selector.
+type selectorTerm struct {
+	key   string
+	value string
+}
+
+// parseSelector splits a selector such as `group=/^DC-.*/ device=*-fw* sensor=Ping*`
+// into its terms. Terms are separated by whitespace or semicolons; regular expressions
+// enclosed in slashes may contain both.
+func parseSelector(selector string) ([]selectorTerm, error) {
+	var terms []selectorTerm
+	rest := strings.TrimSpace(selector)
+
+	for rest != "" {
+		eq := strings.Index(rest, "=")
+		if eq <= 0 {
+			return nil, fmt.Errorf("invalid selector %q: expected key=pattern", rest)
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = rest[eq+1:]
+
+		var value string
+		if strings.HasPrefix(rest, "/") {
+			end := closingSlash(rest)
+			if end < 0 {
+				return nil, fmt.Errorf("invalid selector: unterminated regular expression %q", rest)
+			}
+			value, rest = rest[:end+1], rest[end+1:]
+		} else {
+			end := strings.IndexFunc(rest, isSelectorSeparator)
+			if end < 0 {
+				end = len(rest)
+			}
+			value, rest = rest[:end], rest[end:]
+		}
+		if value == "" {
+			return nil, fmt.Errorf("invalid selector: empty pattern for %q", key)
+		}
+
+		terms = append(terms, selectorTerm{key: key, value: value})
+		rest = strings.TrimLeftFunc(rest, isSelectorSeparator)
+	}
+	return terms, nil
+}
+
+// closingSlash returns the index of the slash that ends the regular expression at
+// the start of s, i.e. the first unescaped slash followed by a separator or the end.
+func closingSlash(s string) int {
+	for i := 1; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == '/' && (i == len(s)-1 || isSelectorSeparator(rune(s[i+1]))) {
+			return i
+		}
+	}
+	return -1
+}
+
+// isSelectorSeparator reports whether r separates selector terms.
+func isSelectorSeparator(r rune) bool {
+	return r == ';' || unicode.IsSpace(r)
+}
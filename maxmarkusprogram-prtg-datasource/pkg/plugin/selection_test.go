@@ -0,0 +1,144 @@
+package plugin
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector string
+		want     []selectorTerm
+		wantErr  bool
+	}{
+		{name: "empty", selector: "", want: nil},
+		{name: "single term", selector: "sensor=Ping", want: []selectorTerm{{"sensor", "Ping"}}},
+		{
+			name:     "whitespace and semicolons",
+			selector: " group=DC-1 ;device=*-fw*\tsensor=Ping* ",
+			want:     []selectorTerm{{"group", "DC-1"}, {"device", "*-fw*"}, {"sensor", "Ping*"}},
+		},
+		{name: "key is case-insensitive", selector: "Group=Core", want: []selectorTerm{{"group", "Core"}}},
+		{name: "pattern keeps its case", selector: "sensor=PING", want: []selectorTerm{{"sensor", "PING"}}},
+		{
+			name:     "regex with separators",
+			selector: "group=/^DC 1;2$/ sensor=Ping",
+			want:     []selectorTerm{{"group", "/^DC 1;2$/"}, {"sensor", "Ping"}},
+		},
+		{name: "regex with inner slash", selector: "device=/a/b/", want: []selectorTerm{{"device", "/a/b/"}}},
+		{name: "regex with escaped slash", selector: `device=/a\/ b/`, want: []selectorTerm{{"device", `/a\/ b/`}}},
+		{name: "unterminated regex", selector: "group=/^DC", wantErr: true},
+		{name: "regex closed only by escaped slash", selector: `group=/^DC\/`, wantErr: true},
+		{name: "missing key", selector: "=Ping", wantErr: true},
+		{name: "missing equals", selector: "Ping", wantErr: true},
+		{name: "empty pattern", selector: "sensor= Ping", wantErr: true},
+		{name: "empty pattern at end", selector: "sensor=", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSelector(tt.selector)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSelector(%q) error = %v, wantErr %v", tt.selector, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("parseSelector(%q) = %v, want %v", tt.selector, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClosingSlash(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{s: "/a/", want: 2},
+		{s: "/a/ rest", want: 2},
+		{s: "/a/;rest", want: 2},
+		{s: "/a/b/", want: 4},
+		{s: "/a/b/ c/", want: 4},
+		{s: `/a\//`, want: 4},
+		{s: `/a\/`, want: -1},
+		{s: "/a", want: -1},
+		{s: "/", want: -1},
+		{s: "//", want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			if got := closingSlash(tt.s); got != tt.want {
+				t.Fatalf("closingSlash(%q) = %d, want %d", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompilePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		matches []string
+		misses  []string
+		wantErr bool
+	}{
+		{pattern: "Ping", matches: []string{"Ping"}, misses: []string{"ping", "Ping 1", "a Ping"}},
+		{pattern: "Ping*", matches: []string{"Ping", "ping 1", "PING v6"}, misses: []string{"My Ping"}},
+		{pattern: "*-fw*", matches: []string{"dc1-fw01", "-FW"}, misses: []string{"dc1-sw01"}},
+		{pattern: "sw?", matches: []string{"sw1", "SWx"}, misses: []string{"sw", "sw12"}},
+		{pattern: "a.b*", matches: []string{"a.b", "a.bc"}, misses: []string{"axb"}},
+		{pattern: "/^DC-[0-9]+$/", matches: []string{"DC-1", "DC-42"}, misses: []string{"dc-1", "DC-"}},
+		{pattern: "/core/", matches: []string{"core", "my core switch"}, misses: []string{"Core"}},
+		{pattern: "/(?i)core/", matches: []string{"Core"}},
+		{pattern: "/", matches: []string{"/"}, misses: []string{""}},
+		{pattern: "/[/", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			p, err := compilePattern(tt.pattern)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("compilePattern(%q) error = %v, wantErr %v", tt.pattern, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			for _, name := range tt.matches {
+				if !p.match(name) {
+					t.Errorf("%q does not match %q", tt.pattern, name)
+				}
+			}
+			for _, name := range tt.misses {
+				if p.match(name) {
+					t.Errorf("%q matches %q", tt.pattern, name)
+				}
+			}
+		})
+	}
+}
+
+func TestSensorSelectionFilter(t *testing.T) {
+	tests := []struct {
+		name string
+		qm   queryModel
+		want url.Values
+	}{
+		{name: "exact groups", qm: queryModel{Groups: []string{"DC-1", "DC-2"}}, want: url.Values{"filter_group": {"DC-1", "DC-2"}}},
+		{name: "exact device from selector", qm: queryModel{Selector: "device=fw-01 sensor=Ping*"}, want: url.Values{"filter_device": {"fw-01"}}},
+		{name: "wildcard keeps the list local", qm: queryModel{Groups: []string{"DC-1", "DC-*"}, Devices: []string{"fw-01"}}, want: url.Values{"filter_device": {"fw-01"}}},
+		{name: "regular expression", qm: queryModel{Selector: "group=/^DC-/"}, want: url.Values{}},
+		{name: "sensor names stay local", qm: queryModel{Sensors: []string{"1001"}}, want: url.Values{}},
+		{name: "tags and names", qm: queryModel{Groups: []string{"DC-1"}, TagFilter: "prod"}, want: url.Values{"filter_group": {"DC-1"}, "filter_tags": {"@tag(prod)"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selection, err := newSensorSelection(tt.qm)
+			if err != nil {
+				t.Fatalf("newSensorSelection() error = %v", err)
+			}
+			got := url.Values{}
+			selection.filter().apply(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("filter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+// flightGroup coalesces concurrent calls with the same key into one execution.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+// flight is one shared execution and the callers still waiting for it.
+type flight struct {
+	ctx     *sharedContext
+	waiters int
+	done    chan struct{}
+	val     interface{}
+	err     error
+}
+
+// shareResult runs fn once for all concurrent callers using the same key, so identical
+// requests share one PRTG round-trip and one decoded result. Callers must treat the
+// result as read-only.
+//
+// fn gets a context that outlives any single caller: it is cancelled only when the last
+// waiting caller gives up, and its deadline is the latest deadline of the callers
+// joined so far. Each caller stops waiting as soon as its own ctx is done.
+func shareResult[T any](ctx context.Context, group *flightGroup, endpoint, key string, fn func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+
+	group.mu.Lock()
+	if group.calls == nil {
+		group.calls = make(map[string]*flight)
+	}
+	f, ok := group.calls[key]
+	if ok {
+		f.ctx.join(ctx)
+	} else {
+		f = &flight{ctx: newSharedContext(ctx), done: make(chan struct{})}
+		group.calls[key] = f
+		go group.run(endpoint, key, f, func(ctx context.Context) (interface{}, error) {
+			return fn(ctx)
+		})
+	}
+	f.waiters++
+	group.mu.Unlock()
+
+	select {
+	case <-f.done:
+		if f.err != nil {
+			return zero, f.err
+		}
+		return f.val.(T), nil
+	case <-ctx.Done():
+		cause := context.Cause(ctx)
+		group.leave(key, f, cause)
+		return zero, &APIError{Endpoint: endpoint, Kind: transportErrorKind(cause), Err: fmt.Errorf("request aborted: %w", cause)}
+	}
+}
+
+// run executes the shared call and publishes its result to all waiters. It runs on its
+// own goroutine, outside the recover of the query, so a panic in fn becomes the error of
+// the call instead of crashing the plugin.
+func (g *flightGroup) run(endpoint, key string, f *flight, fn func(ctx context.Context) (interface{}, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			backend.Logger.Error("Shared request panicked", "endpoint", endpoint, "panic", r)
+			f.val, f.err = nil, &APIError{Endpoint: endpoint, Err: fmt.Errorf("internal error: %v", r)}
+		}
+		f.ctx.cancel(context.Canceled)
+
+		g.mu.Lock()
+		if g.calls[key] == f {
+			delete(g.calls, key)
+		}
+		g.mu.Unlock()
+		close(f.done)
+	}()
+	f.val, f.err = fn(f.ctx)
+}
+
+// leave removes one waiter and cancels the shared call once nobody waits for it anymore.
+// The call is forgotten at the same time, so later callers start a fresh one.
+func (g *flightGroup) leave(key string, f *flight, cause error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	f.waiters--
+	if f.waiters > 0 {
+		return
+	}
+	if g.calls[key] == f {
+		delete(g.calls, key)
+	}
+	f.ctx.cancel(cause)
+}
+
+// sharedContext is the context of a shared call. It keeps the values of the caller that
+// started the call, but none of its cancellation: it is cancelled explicitly and at the
+// latest deadline of all callers that joined it.
+type sharedContext struct {
+	context.Context
+	cancel context.CancelCauseFunc
+
+	mu       sync.Mutex
+	joined   int
+	bounded  bool
+	deadline time.Time
+	timer    *time.Timer
+}
+
+func newSharedContext(parent context.Context) *sharedContext {
+	ctx, cancel := context.WithCancelCause(context.WithoutCancel(parent))
+	c := &sharedContext{Context: ctx, cancel: cancel}
+	c.join(parent)
+	return c
+}
+
+// join carries the deadline of another caller onto the shared context. A caller without
+// a deadline removes it altogether.
+func (c *sharedContext) join(ctx context.Context) {
+	deadline, ok := ctx.Deadline()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.joined == 0 {
+		c.bounded = ok
+	} else if !ok {
+		c.bounded = false
+	}
+	c.joined++
+
+	if !c.bounded {
+		if c.timer != nil {
+			c.timer.Stop()
+			c.timer = nil
+		}
+		return
+	}
+	if !deadline.After(c.deadline) {
+		return
+	}
+	c.deadline = deadline
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	c.timer = time.AfterFunc(time.Until(deadline), func() {
+		c.cancel(context.DeadlineExceeded)
+	})
+}
+
+// Deadline reports the latest deadline of the joined callers, if all of them have one.
+func (c *sharedContext) Deadline() (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.bounded {
+		return time.Time{}, false
+	}
+	return c.deadline, true
+}
+
+// Err reports context.DeadlineExceeded once the shared deadline has passed, so timeouts
+// keep their kind.
+func (c *sharedContext) Err() error {
+	err := c.Context.Err()
+	if err != nil && errors.Is(context.Cause(c.Context), context.DeadlineExceeded) {
+		return context.DeadlineExceeded
+	}
+	return err
+}
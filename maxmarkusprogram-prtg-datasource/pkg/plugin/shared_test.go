@@ -0,0 +1,191 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingServer answers nothing until the request is cancelled and reports every
+// cancelled request on the returned channel.
+func blockingServer(t *testing.T) (*httptest.Server, <-chan struct{}, <-chan struct{}) {
+	t.Helper()
+	started := make(chan struct{}, 10)
+	cancelled := make(chan struct{}, 10)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		select {
+		case <-r.Context().Done():
+			cancelled <- struct{}{}
+		case <-release:
+		}
+	}))
+	t.Cleanup(func() {
+		close(release)
+		srv.Close()
+	})
+	return srv, started, cancelled
+}
+
+func waitFor(t *testing.T, ch <-chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+	}
+}
+
+// waitWaiters blocks until n callers wait for the shared call with the given key.
+func waitWaiters(t *testing.T, group *flightGroup, key string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		group.mu.Lock()
+		f := group.calls[key]
+		waiting := f != nil && f.waiters == n
+		group.mu.Unlock()
+		if waiting {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d callers of %q", n, key)
+}
+
+func TestShareResultCancelsRequestWhenLastCallerLeaves(t *testing.T) {
+	srv, started, cancelled := blockingServer(t)
+	api := NewApi(srv.URL, Credentials{}, srv.Client(), 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, err := api.GetGroups(ctx, nil)
+		errc <- err
+	}()
+
+	waitFor(t, started, "the request to start")
+	cancel()
+	waitFor(t, cancelled, "the server to see the cancellation")
+
+	err := <-errc
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetGroups() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestShareResultKeepsRequestForRemainingCallers(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		select {
+		case <-release:
+			_, _ = w.Write([]byte(`{"prtg-version":"1","treesize":0,"groups":[]}`))
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	api := NewApi(srv.URL, Credentials{}, srv.Client(), 0)
+
+	var group flightGroup
+	gate := make(chan struct{})
+	fn := func(ctx context.Context) ([]byte, error) {
+		<-gate
+		return api.doRequest(ctx, "table.json", nil)
+	}
+
+	leaving, cancel := context.WithCancel(context.Background())
+	leftc := make(chan error, 1)
+	go func() {
+		_, err := shareResult(leaving, &group, "table.json", "key", fn)
+		leftc <- err
+	}()
+	waitWaiters(t, &group, "key", 1)
+	staying := make(chan error, 1)
+	go func() {
+		_, err := shareResult(context.Background(), &group, "table.json", "key", fn)
+		staying <- err
+	}()
+	waitWaiters(t, &group, "key", 2)
+
+	cancel()
+	if err := <-leftc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled caller error = %v, want context.Canceled", err)
+	}
+	close(gate)
+	close(release)
+
+	if err := <-staying; err != nil {
+		t.Fatalf("remaining caller error = %v, want nil", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("server saw %d requests, want 1", got)
+	}
+}
+
+func TestSharedContextDeadline(t *testing.T) {
+	now := time.Now()
+	early, cancelEarly := context.WithDeadline(context.Background(), now.Add(time.Hour))
+	defer cancelEarly()
+	late, cancelLate := context.WithDeadline(context.Background(), now.Add(2*time.Hour))
+	defer cancelLate()
+
+	tests := []struct {
+		name    string
+		callers []context.Context
+		want    time.Time
+		wantOk  bool
+	}{
+		{name: "single caller", callers: []context.Context{early}, want: now.Add(time.Hour), wantOk: true},
+		{name: "latest deadline wins", callers: []context.Context{early, late, early}, want: now.Add(2 * time.Hour), wantOk: true},
+		{name: "caller without deadline", callers: []context.Context{early, context.Background()}, wantOk: false},
+		{name: "first caller without deadline", callers: []context.Context{context.Background(), late}, wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newSharedContext(tt.callers[0])
+			defer c.cancel(context.Canceled)
+			for _, ctx := range tt.callers[1:] {
+				c.join(ctx)
+			}
+			got, ok := c.Deadline()
+			if ok != tt.wantOk || !got.Equal(tt.want) {
+				t.Fatalf("Deadline() = %v, %v; want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestSharedContextExpires(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	c := newSharedContext(ctx)
+
+	waitFor(t, c.Done(), "the shared deadline")
+	if !errors.Is(c.Err(), context.DeadlineExceeded) {
+		t.Fatalf("Err() = %v, want context.DeadlineExceeded", c.Err())
+	}
+}
+
+func TestShareResultRecoversPanic(t *testing.T) {
+	var group flightGroup
+	_, err := shareResult(context.Background(), &group, "table.json", "key", func(ctx context.Context) ([]byte, error) {
+		panic("boom")
+	})
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Endpoint != "table.json" {
+		t.Fatalf("shareResult() error = %v, want an APIError for table.json", err)
+	}
+	group.mu.Lock()
+	defer group.mu.Unlock()
+	if _, ok := group.calls["key"]; ok {
+		t.Fatal("panicked call is still registered")
+	}
+}
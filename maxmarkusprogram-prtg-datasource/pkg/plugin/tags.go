@@ -0,0 +1,243 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// tagExpr is a boolean expression over PRTG tags, e.g. `core-switch AND (snmptraffic OR NOT lab)`.
+type tagExpr interface {
+	eval(tags map[string]bool) bool
+}
+
+type tagLiteral string
+
+func (t tagLiteral) eval(tags map[string]bool) bool { return tags[string(t)] }
+
+type tagNot struct{ expr tagExpr }
+
+func (n tagNot) eval(tags map[string]bool) bool { return !n.expr.eval(tags) }
+
+type tagAnd []tagExpr
+
+func (a tagAnd) eval(tags map[string]bool) bool {
+	for _, e := range a {
+		if !e.eval(tags) {
+			return false
+		}
+	}
+	return true
+}
+
+type tagOr []tagExpr
+
+func (o tagOr) eval(tags map[string]bool) bool {
+	for _, e := range o {
+		if e.eval(tags) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTagFilter parses a tag filter. Operators are AND, OR and NOT (case-insensitive,
+// also "&&", "||" and "!"), parentheses group terms and adjacent tags are combined with AND.
+// Tags are compared case-insensitively, as PRTG does. An empty filter returns nil.
+func parseTagFilter(filter string) (tagExpr, error) {
+	tokens := tokenizeTagFilter(filter)
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	p := &tagParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid tag filter %q: %w", filter, err)
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("invalid tag filter %q: unexpected %q", filter, p.tokens[p.pos])
+	}
+	return expr, nil
+}
+
+// matchTags evaluates expr against a space-separated PRTG tag list. A nil expression matches everything.
+func matchTags(expr tagExpr, tags string) bool {
+	if expr == nil {
+		return true
+	}
+	return expr.eval(splitTags(tags))
+}
+
+// requiredTags returns tags of which every object matching expr carries at least one,
+// so PRTG can pre-filter rows with filter_tags. It returns nil when no such set exists,
+// e.g. for NOT terms, and the expression must still be evaluated on the returned rows.
+func requiredTags(expr tagExpr) []string {
+	switch e := expr.(type) {
+	case tagLiteral:
+		return []string{string(e)}
+	case tagAnd:
+		// Any operand of an AND is necessary, the first usable one is enough.
+		for _, operand := range e {
+			if tags := requiredTags(operand); tags != nil {
+				return tags
+			}
+		}
+	case tagOr:
+		var tags []string
+		for _, operand := range e {
+			operandTags := requiredTags(operand)
+			if operandTags == nil {
+				return nil
+			}
+			tags = append(tags, operandTags...)
+		}
+		return tags
+	}
+	return nil
+}
+
+// splitTags converts a PRTG tag string into a lookup set of lower-cased tags.
+func splitTags(tags string) map[string]bool {
+	set := make(map[string]bool)
+	for _, tag := range strings.FieldsFunc(tags, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		set[strings.ToLower(tag)] = true
+	}
+	return set
+}
+
+// tokenizeTagFilter splits a tag filter into tags, operators and parentheses.
+func tokenizeTagFilter(filter string) []string {
+	var tokens []string
+	var current strings.Builder
+
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+
+	runes := []rune(filter)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r) || r == ',':
+			flush()
+		case r == '(' || r == ')':
+			flush()
+			tokens = append(tokens, string(r))
+		case r == '!' && current.Len() == 0:
+			tokens = append(tokens, "NOT")
+		case (r == '&' || r == '|') && i+1 < len(runes) && runes[i+1] == r:
+			flush()
+			if r == '&' {
+				tokens = append(tokens, "AND")
+			} else {
+				tokens = append(tokens, "OR")
+			}
+			i++
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+// tagParser is a recursive descent parser for tag filters.
+type tagParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *tagParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *tagParser) isKeyword(token, keyword string) bool {
+	return strings.EqualFold(token, keyword)
+}
+
+// parseOr parses: and { OR and }
+func (p *tagParser) parseOr() (tagExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	terms := tagOr{left}
+	for p.isKeyword(p.peek(), "OR") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, right)
+	}
+	if len(terms) == 1 {
+		return left, nil
+	}
+	return terms, nil
+}
+
+// parseAnd parses: unary { [AND] unary }
+func (p *tagParser) parseAnd() (tagExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	terms := tagAnd{left}
+	for {
+		next := p.peek()
+		if next == "" || next == ")" || p.isKeyword(next, "OR") {
+			break
+		}
+		if p.isKeyword(next, "AND") {
+			p.pos++
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, right)
+	}
+	if len(terms) == 1 {
+		return left, nil
+	}
+	return terms, nil
+}
+
+// parseUnary parses: NOT unary | ( or ) | tag
+func (p *tagParser) parseUnary() (tagExpr, error) {
+	token := p.peek()
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("unexpected end of filter")
+	case p.isKeyword(token, "NOT"):
+		p.pos++
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return tagNot{expr}, nil
+	case token == "(":
+		p.pos++
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return expr, nil
+	case token == ")" || p.isKeyword(token, "AND") || p.isKeyword(token, "OR"):
+		return nil, fmt.Errorf("unexpected %q", token)
+	default:
+		p.pos++
+		return tagLiteral(strings.ToLower(token)), nil
+	}
+}
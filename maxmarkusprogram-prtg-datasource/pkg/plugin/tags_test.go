@@ -0,0 +1,218 @@
+package plugin
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// formatTagExpr renders an expression in prefix notation, e.g. "(or a (and b (not c)))".
+func formatTagExpr(expr tagExpr) string {
+	join := func(op string, operands []tagExpr) string {
+		parts := []string{op}
+		for _, operand := range operands {
+			parts = append(parts, formatTagExpr(operand))
+		}
+		return "(" + strings.Join(parts, " ") + ")"
+	}
+
+	switch e := expr.(type) {
+	case nil:
+		return ""
+	case tagLiteral:
+		return string(e)
+	case tagNot:
+		return "(not " + formatTagExpr(e.expr) + ")"
+	case tagAnd:
+		return join("and", e)
+	case tagOr:
+		return join("or", e)
+	}
+	return "?"
+}
+
+func TestTokenizeTagFilter(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		{filter: "", want: nil},
+		{filter: "  ", want: nil},
+		{filter: "a", want: []string{"a"}},
+		{filter: "a b,c", want: []string{"a", "b", "c"}},
+		{filter: "(a OR b)", want: []string{"(", "a", "OR", "b", ")"}},
+		{filter: "a&&b||c", want: []string{"a", "AND", "b", "OR", "c"}},
+		{filter: "a && !b", want: []string{"a", "AND", "NOT", "b"}},
+		{filter: "!(a)", want: []string{"NOT", "(", "a", ")"}},
+		{filter: "!!a", want: []string{"NOT", "NOT", "a"}},
+		{filter: "no!t", want: []string{"no!t"}},
+		{filter: "a&b|c", want: []string{"a&b|c"}},
+		{filter: "core-switch snmp_traffic", want: []string{"core-switch", "snmp_traffic"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			if got := tokenizeTagFilter(tt.filter); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("tokenizeTagFilter(%q) = %q, want %q", tt.filter, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTagFilter(t *testing.T) {
+	tests := []struct {
+		filter  string
+		want    string
+		wantErr bool
+	}{
+		{filter: "", want: ""},
+		{filter: "Core", want: "core"},
+		{filter: "a b", want: "(and a b)"},
+		{filter: "a AND b", want: "(and a b)"},
+		{filter: "a and b && c", want: "(and a b c)"},
+		{filter: "a OR b", want: "(or a b)"},
+		{filter: "a || b or c", want: "(or a b c)"},
+		{filter: "a OR b AND c", want: "(or a (and b c))"},
+		{filter: "a AND b OR c", want: "(or (and a b) c)"},
+		{filter: "a b OR c d", want: "(or (and a b) (and c d))"},
+		{filter: "(a OR b) c", want: "(and (or a b) c)"},
+		{filter: "NOT a b", want: "(and (not a) b)"},
+		{filter: "NOT (a OR b)", want: "(not (or a b))"},
+		{filter: "!a || !b", want: "(or (not a) (not b))"},
+		{filter: "not not a", want: "(not (not a))"},
+		{filter: "((a))", want: "a"},
+		{filter: "a OR", wantErr: true},
+		{filter: "AND a", wantErr: true},
+		{filter: "a AND OR b", wantErr: true},
+		{filter: "NOT", wantErr: true},
+		{filter: "(a", wantErr: true},
+		{filter: "a)", wantErr: true},
+		{filter: "()", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			expr, err := parseTagFilter(tt.filter)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTagFilter(%q) error = %v, wantErr %v", tt.filter, err, tt.wantErr)
+			}
+			if got := formatTagExpr(expr); !tt.wantErr && got != tt.want {
+				t.Fatalf("parseTagFilter(%q) = %s, want %s", tt.filter, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchTags(t *testing.T) {
+	tests := []struct {
+		filter string
+		tags   string
+		want   bool
+	}{
+		{filter: "", tags: "", want: true},
+		{filter: "core", tags: "Core snmp", want: true},
+		{filter: "core", tags: "coreswitch", want: false},
+		{filter: "core snmp", tags: "core,snmp", want: true},
+		{filter: "core snmp", tags: "core", want: false},
+		{filter: "core OR lab", tags: "lab", want: true},
+		{filter: "NOT lab", tags: "core", want: true},
+		{filter: "NOT lab", tags: "core lab", want: false},
+		{filter: "core AND NOT (lab OR test)", tags: "core test", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter+"/"+tt.tags, func(t *testing.T) {
+			expr, err := parseTagFilter(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := matchTags(expr, tt.tags); got != tt.want {
+				t.Fatalf("matchTags(%q, %q) = %v, want %v", tt.filter, tt.tags, got, tt.want)
+			}
+		})
+	}
+}
+
+// pushdownFilters are the tag filters used to check the server-side pre-filter.
+var pushdownFilters = []string{
+	"a",
+	"a b",
+	"a OR b",
+	"a OR b c",
+	"(a OR b) c",
+	"(a OR b) (c OR d)",
+	"a OR (b AND NOT c)",
+	"NOT a",
+	"NOT a b",
+	"(NOT a) OR b",
+	"NOT (a OR b) c",
+	"a OR NOT b",
+	"NOT NOT a",
+	"(a b) OR (c d)",
+}
+
+func TestRequiredTags(t *testing.T) {
+	want := map[string][]string{
+		"a":                  {"a"},
+		"a b":                {"a"},
+		"a OR b":             {"a", "b"},
+		"a OR b c":           {"a", "b"},
+		"(a OR b) c":         {"a", "b"},
+		"(a OR b) (c OR d)":  {"a", "b"},
+		"a OR (b AND NOT c)": {"a", "b"},
+		"NOT a":              nil,
+		"NOT a b":            {"b"},
+		"(NOT a) OR b":       nil,
+		"NOT (a OR b) c":     {"c"},
+		"a OR NOT b":         nil,
+		"NOT NOT a":          nil,
+		"(a b) OR (c d)":     {"a", "c"},
+	}
+	for _, filter := range pushdownFilters {
+		t.Run(filter, func(t *testing.T) {
+			expr, err := parseTagFilter(filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := requiredTags(expr)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, want[filter]) {
+				t.Fatalf("requiredTags(%q) = %q, want %q", filter, got, want[filter])
+			}
+		})
+	}
+}
+
+// TestRequiredTagsNeverDropsMatches checks that every tag set matching a filter carries
+// one of its required tags, so the filter_tags pre-filter keeps all matching rows.
+func TestRequiredTagsNeverDropsMatches(t *testing.T) {
+	universe := []string{"a", "b", "c", "d"}
+	for _, filter := range pushdownFilters {
+		expr, err := parseTagFilter(filter)
+		if err != nil {
+			t.Fatalf("parseTagFilter(%q): %v", filter, err)
+		}
+		required := requiredTags(expr)
+		if required == nil {
+			continue
+		}
+
+		for mask := 0; mask < 1<<len(universe); mask++ {
+			var tags []string
+			for i, tag := range universe {
+				if mask&(1<<i) != 0 {
+					tags = append(tags, tag)
+				}
+			}
+			set := splitTags(strings.Join(tags, " "))
+			if !expr.eval(set) {
+				continue
+			}
+			kept := false
+			for _, tag := range required {
+				kept = kept || set[tag]
+			}
+			if !kept {
+				t.Errorf("filter %q: pre-filter on %q drops matching tags %q", filter, required, tags)
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrics of the PRTG API client. They are registered with the default Prometheus registry,
+// which the plugin SDK exposes through Grafana's plugin metrics endpoint
+// (/api/plugins/<plugin id>/metrics).
+var (
+	apiRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "grafana_plugin",
+		Subsystem: "prtg_api",
+		Name:      "requests_total",
+		Help:      "Number of HTTP requests sent to PRTG by endpoint and status code (\"none\" if no response was received).",
+	}, []string{"endpoint", "status_code"})
+
+	apiRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "grafana_plugin",
+		Subsystem: "prtg_api",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of HTTP requests to PRTG including reading the response body.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"endpoint"})
+
+	apiErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "grafana_plugin",
+		Subsystem: "prtg_api",
+		Name:      "errors_total",
+		Help:      "Number of failed PRTG API requests by endpoint and error kind.",
+	}, []string{"endpoint", "kind"})
+
+	apiResponseBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "grafana_plugin",
+		Subsystem: "prtg_api",
+		Name:      "response_bytes_total",
+		Help:      "Number of response body bytes received from PRTG.",
+	}, []string{"endpoint"})
+
+	apiCacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "grafana_plugin",
+		Subsystem: "prtg_api",
+		Name:      "cache_lookups_total",
+		Help:      "Number of response cache lookups by endpoint and result (hit or miss).",
+	}, []string{"endpoint", "result"})
+
+	apiRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "grafana_plugin",
+		Subsystem: "prtg_api",
+		Name:      "retries_total",
+		Help:      "Number of retried PRTG API requests.",
+	}, []string{"endpoint"})
+)
+
+// observeRequest records an HTTP request to PRTG. statusCode is 0 if no response was received.
+func observeRequest(endpoint string, statusCode int, duration time.Duration, bytes int) {
+	code := "none"
+	if statusCode != 0 {
+		code = strconv.Itoa(statusCode)
+	}
+	apiRequests.WithLabelValues(endpoint, code).Inc()
+	apiRequestDuration.WithLabelValues(endpoint).Observe(duration.Seconds())
+	if bytes > 0 {
+		apiResponseBytes.WithLabelValues(endpoint).Add(float64(bytes))
+	}
+}
+
+// observeError records a failed PRTG API request.
+func observeError(endpoint string, err error) {
+	apiErrors.WithLabelValues(endpoint, errorKind(err)).Inc()
+}
+
+// observeCacheLookup records a response cache hit or miss.
+func observeCacheLookup(endpoint string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	apiCacheLookups.WithLabelValues(endpoint, result).Inc()
+}
+
+// observeRetry records a retried request.
+func observeRetry(endpoint string) {
+	apiRetries.WithLabelValues(endpoint).Inc()
+}
+
+// errorKind returns a low-cardinality label for an error.
+func errorKind(err error) string {
+	switch {
+	case errors.Is(err, ErrUnauthorized):
+		return "unauthorized"
+	case errors.Is(err, ErrForbidden):
+		return "forbidden"
+	case errors.Is(err, ErrNotFound):
+		return "not_found"
+	case errors.Is(err, ErrTimeout):
+		return "timeout"
+	case errors.Is(err, ErrOverloaded):
+		return "overloaded"
+	case errors.Is(err, ErrMalformedResponse):
+		return "malformed_response"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode != 0 {
+		return "http_" + strconv.Itoa(apiErr.StatusCode)
+	}
+	return "other"
+}
@@ -0,0 +1,79 @@
+package plugin
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
+	"github.com/maxmarkusprogram/prtg/pkg/models"
+)
+
+// newTLSConfig builds the TLS configuration for PRTG requests from the plugin settings.
+// Certificate verification is enabled unless TLSSkipVerify is set explicitly.
+func newTLSConfig(config *models.PluginSettings) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: config.TLSSkipVerify, //nolint:gosec // opt-in via settings
+		ServerName:         config.TLSServerName,
+	}
+
+	if config.TLSAuthWithCACert {
+		if config.Secrets.TLSCACert == "" {
+			return nil, errors.New("TLS CA certificate is enabled but not configured")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(config.Secrets.TLSCACert)) {
+			return nil, errors.New("failed to parse TLS CA certificate: no valid PEM certificate found")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if config.TLSAuth {
+		if config.Secrets.TLSClientCert == "" || config.Secrets.TLSClientKey == "" {
+			return nil, errors.New("TLS client authentication is enabled but the client certificate or key is missing")
+		}
+		cert, err := tls.X509KeyPair([]byte(config.Secrets.TLSClientCert), []byte(config.Secrets.TLSClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// configureTLS returns a hook that applies tlsConfig to the tls.Config created by the SDK httpclient.
+func configureTLS(tlsConfig *tls.Config) httpclient.ConfigureTLSConfigFunc {
+	return func(_ httpclient.Options, c *tls.Config) {
+		c.MinVersion = tlsConfig.MinVersion
+		c.InsecureSkipVerify = tlsConfig.InsecureSkipVerify //nolint:gosec // opt-in via settings
+		c.ServerName = tlsConfig.ServerName
+		c.RootCAs = tlsConfig.RootCAs
+		c.Certificates = tlsConfig.Certificates
+	}
+}
+
+// describeTLSError returns a user-facing description if err was caused by a
+// failed certificate verification.
+func describeTLSError(err error) (string, bool) {
+	var (
+		unknownAuthority x509.UnknownAuthorityError
+		hostnameErr      x509.HostnameError
+		invalidCert      x509.CertificateInvalidError
+		verifyErr        *tls.CertificateVerificationError
+	)
+
+	switch {
+	case errors.As(err, &unknownAuthority):
+		return "the PRTG server certificate is signed by an unknown authority; configure the CA certificate or enable \"Skip TLS verification\"", true
+	case errors.As(err, &hostnameErr):
+		return fmt.Sprintf("the PRTG server certificate is not valid for this host (%v); check the server address or the TLS server name", hostnameErr), true
+	case errors.As(err, &invalidCert):
+		return fmt.Sprintf("the PRTG server certificate is invalid: %v", invalidCert), true
+	case errors.As(err, &verifyErr):
+		return fmt.Sprintf("TLS certificate verification failed: %v", verifyErr.Err), true
+	}
+	return "", false
+}
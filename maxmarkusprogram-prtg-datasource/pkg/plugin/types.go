@@ -42,6 +42,7 @@ type PrtgGroupListItemStruct struct {
 	MessageRAW     string  `json:"message_raw" xml:"message_raw"`
 	ObjectId       int64   `json:"objid" xml:"objid"`
 	ObjectIdRAW    int64   `json:"objid_raw" xml:"objid_raw"`
+	ParentId       int64   `json:"parentid" xml:"parentid"`
 	Pausedsens     string  `json:"pausedsens" xml:"pausedsens"`
 	PausedsensRAW  int     `json:"pausedsens_raw" xml:"pausedsens_raw"`
 	Priority       string  `json:"priority" xml:"priority"`
@@ -89,6 +90,7 @@ type PrtgDeviceListItemStruct struct {
 	MessageRAW     string  `json:"message_raw" xml:"message_raw"`
 	ObjectId       int64   `json:"objid" xml:"objid"`
 	ObjectIdRAW    int64   `json:"objid_raw" xml:"objid_raw"`
+	ParentId       int64   `json:"parentid" xml:"parentid"`
 	Pausedsens     string  `json:"pausedsens" xml:"pausedsens"`
 	PausedsensRAW  int     `json:"pausedsens_raw" xml:"pausedsens_raw"`
 	Priority       string  `json:"priority" xml:"priority"`
@@ -136,6 +138,7 @@ type PrtgSensorListItemStruct struct {
 	MessageRAW     string  `json:"message_raw" xml:"message_raw"`
 	ObjectId       int64   `json:"objid" xml:"objid"`
 	ObjectIdRAW    int64   `json:"objid_raw" xml:"objid_raw"`
+	ParentId       int64   `json:"parentid" xml:"parentid"`
 	Pausedsens     string  `json:"pausedsens" xml:"pausedsens"`
 	PausedsensRAW  int     `json:"pausedsens_raw" xml:"pausedsens_raw"`
 	Priority       string  `json:"priority" xml:"priority"`
@@ -195,6 +198,30 @@ type PrtgStatusListResponse struct {
 	WarnSens             string `json:"warnsens"`
 }
 
+//############################# MESSAGE LIST RESPONSE ####################################
+
+// PrtgMessagesListResponse repräsentiert die Antwort für Log-Meldungen.
+type PrtgMessagesListResponse struct {
+	PrtgVersion string                      `json:"prtg-version" xml:"prtg-version"`
+	TreeSize    int64                       `json:"treesize" xml:"treesize"`
+	Messages    []PrtgMessageListItemStruct `json:"messages" xml:"messages"`
+}
+
+// PrtgMessageListItemStruct enthält Details zu einer einzelnen Log-Meldung.
+type PrtgMessageListItemStruct struct {
+	ObjectId    int64   `json:"objid" xml:"objid"`
+	Datetime    string  `json:"datetime" xml:"datetime"`
+	DatetimeRAW float64 `json:"datetime_raw" xml:"datetime_raw"`
+	Parent      string  `json:"parent" xml:"parent"`
+	Type        string  `json:"type" xml:"type"`
+	TypeRAW     int     `json:"type_raw" xml:"type_raw"`
+	Name        string  `json:"name" xml:"name"`
+	Status      string  `json:"status" xml:"status"`
+	StatusRAW   int     `json:"status_raw" xml:"status_raw"`
+	Message     string  `json:"message" xml:"message"`
+	MessageRAW  string  `json:"message_raw" xml:"message_raw"`
+}
+
 //############################# CHANNEL LIST RESPONSE ####################################
 
 // PrtgChannelsListResponse repräsentiert die Antwort für Channel-Werte.
@@ -207,6 +234,29 @@ type PrtgChannelsListResponse struct {
 // PrtgChannelValueStruct wird als dynamische Map zur Speicherung von Channel-Daten verwendet.
 type PrtgChannelValueStruct map[string]interface{}
 
+// PrtgChannelMetaListResponse repräsentiert die Kanal-Tabelle eines Sensors (content=channels).
+type PrtgChannelMetaListResponse struct {
+	PrtgVersion string                      `json:"prtg-version" xml:"prtg-version"`
+	TreeSize    int64                       `json:"treesize" xml:"treesize"`
+	Channels    []PrtgChannelMetaItemStruct `json:"channels" xml:"channels"`
+}
+
+// PrtgChannelMetaItemStruct enthält Name und letzten Wert eines Kanals.
+// lastvalue enthält den formatierten Wert samt Einheit, z. B. "12 Mbit/s", lastvalue_raw den
+// Rohwert in der Skala der historischen Daten.
+type PrtgChannelMetaItemStruct struct {
+	ObjectId     int64       `json:"objid" xml:"objid"`
+	Name         string      `json:"name" xml:"name"`
+	LastValue    string      `json:"lastvalue" xml:"lastvalue"`
+	LastValueRAW interface{} `json:"lastvalue_raw" xml:"lastvalue_raw"`
+}
+
+// PrtgObjectPropertyResponse ist die Antwort von getobjectproperty.htm.
+type PrtgObjectPropertyResponse struct {
+	Version string `xml:"version"`
+	Result  string `xml:"result"`
+}
+
 //############################# CHANNEL VALUE RESPONSE ####################################
 
 // PrtgHistoricalDataResponse enthält historische Werte eines Sensors.
@@ -214,6 +264,8 @@ type PrtgHistoricalDataResponse struct {
 	PrtgVersion string       `json:"prtg-version" xml:"prtg-version"`
 	TreeSize    int64        `json:"treesize" xml:"treesize"`
 	HistData    []PrtgValues `json:"histdata" xml:"histdata"`
+	// Truncated ist gesetzt, wenn PRTG mindestens eine Teilabfrage gekürzt hat.
+	Truncated bool `json:"-" xml:"-"`
 }
 
 // PrtgValues enthält den Zeitstempel und dynamische Werte.
@@ -241,8 +293,12 @@ func (p *PrtgValues) UnmarshalJSON(data []byte) error {
 
 // Datasource definiert grundlegende Parameter für die Datasource.
 type Datasource struct {
-	baseURL string
-	api     *Api
+	baseURL              string
+	api                  *Api
+	maxConcurrentQueries int
+	resolveCache         *ttlCache[[]metricTarget]
+	channelMetaCache     *ttlCache[map[string]channelMeta]
+	channelLimitCache    *ttlCache[*channelLimits]
 }
 
 // Group, Device und Sensor dienen als einfache Strukturen zur Filterung.
@@ -266,6 +322,7 @@ type queryModel struct {
 	Device            string   `json:"device"`
 	Sensor            string   `json:"sensor"`
 	Channel           string   `json:"channel"`
+	Channels          []string `json:"channels,omitempty"`
 	Property          string   `json:"property"`
 	FilterProperty    string   `json:"filterProperty"`
 	IncludeGroupName  bool     `json:"includeGroupName"`
@@ -274,6 +331,11 @@ type queryModel struct {
 	Groups            []string `json:"groups,omitempty"`
 	Devices           []string `json:"devices,omitempty"`
 	Sensors           []string `json:"sensors,omitempty"`
+	Selector          string   `json:"selector,omitempty"`
+	TagFilter         string   `json:"tagFilter,omitempty"`
+	VariableType      string   `json:"variableType,omitempty"`
+	Parent            string   `json:"parent,omitempty"`
+	Averaging         string   `json:"averaging,omitempty"`
 	From              int64    `json:"from"`
 	To                int64    `json:"to"`
 }
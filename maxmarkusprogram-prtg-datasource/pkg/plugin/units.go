@@ -0,0 +1,328 @@
+package plugin
+
+import (
+	"context"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// channelMetadataTTL is how long the channel units and limits of a sensor are cached.
+// They only change when a channel is reconfigured, so they are kept longer than responses.
+const channelMetadataTTL = 15 * time.Minute
+
+// channelMeta holds the display settings of a channel derived from PRTG.
+type channelMeta struct {
+	// ObjectId is the channel id within its sensor.
+	ObjectId int64
+	// Unit is the Grafana unit of the raw values, empty if unknown.
+	Unit string
+	// Scale converts a raw value into the unit PRTG displays; 0 if unknown.
+	Scale float64
+	// Decimals is only set when raw and displayed values share their scale.
+	Decimals *uint16
+	// Limits are the channel limits configured in PRTG, in raw values.
+	Limits *channelLimits
+}
+
+// channelLimits are the warning and error limits of a channel. Unset limits are nil.
+type channelLimits struct {
+	MinError   *float64
+	MinWarning *float64
+	MaxWarning *float64
+	MaxError   *float64
+}
+
+// unitScale is a possible meaning of a PRTG unit suffix: raw values in Grafana unit
+// Unit are displayed by PRTG after multiplying them with Factor.
+type unitScale struct {
+	Unit   string
+	Factor float64
+}
+
+// prtgUnitScales maps PRTG unit suffixes to the raw units they may be displayed from.
+// PRTG shows traffic in bits while some sensors deliver bytes, and sizes in decimal or
+// binary multiples, so several scales are possible and the raw value decides.
+var prtgUnitScales = map[string][]unitScale{
+	"%":       {{"percent", 1}},
+	"msec":    {{"ms", 1}},
+	"ms":      {{"ms", 1}},
+	"sec":     {{"s", 1}},
+	"s":       {{"s", 1}},
+	"bit/s":   {{"bps", 1}, {"Bps", 8}},
+	"kbit/s":  {{"bps", 1e-3}, {"Bps", 8e-3}},
+	"Mbit/s":  {{"bps", 1e-6}, {"Bps", 8e-6}},
+	"Gbit/s":  {{"bps", 1e-9}, {"Bps", 8e-9}},
+	"Byte":    {{"decbytes", 1}},
+	"KByte":   {{"decbytes", 1e-3}, {"bytes", 1.0 / (1 << 10)}},
+	"MByte":   {{"decbytes", 1e-6}, {"bytes", 1.0 / (1 << 20)}},
+	"GByte":   {{"decbytes", 1e-9}, {"bytes", 1.0 / (1 << 30)}},
+	"TByte":   {{"decbytes", 1e-12}, {"bytes", 1.0 / (1 << 40)}},
+	"Byte/s":  {{"Bps", 1}},
+	"KByte/s": {{"Bps", 1e-3}, {"binBps", 1.0 / (1 << 10)}},
+	"MByte/s": {{"Bps", 1e-6}, {"binBps", 1.0 / (1 << 20)}},
+	"GByte/s": {{"Bps", 1e-9}, {"binBps", 1.0 / (1 << 30)}},
+	"°C":      {{"celsius", 1}},
+	"°F":      {{"fahrenheit", 1}},
+	"V":       {{"volt", 1}},
+	"A":       {{"amp", 1}},
+	"W":       {{"watt", 1}},
+	"Hz":      {{"hertz", 1}},
+	"rpm":     {{"rotrpm", 1}},
+	"#":       {{"none", 1}},
+}
+
+// lastValuePattern splits a formatted PRTG value such as "1.234,5 MByte" into number and unit.
+var lastValuePattern = regexp.MustCompile(`^\s*(-?[\d.,]+)\s*(.*?)\s*$`)
+
+// displayedNumber is one reading of a formatted number.
+type displayedNumber struct {
+	value    float64
+	decimals uint16
+}
+
+// parseLastValue derives the unit and decimals of a channel from its formatted last value
+// and the raw value behind it. Historic data holds raw values, so the displayed unit only
+// applies after checking which scale turns the raw value into the displayed one. A unit
+// is set only if exactly one scale matches; decimals only if the scales are equal.
+func parseLastValue(lastValue string, rawValue interface{}) (channelMeta, bool) {
+	match := lastValuePattern.FindStringSubmatch(lastValue)
+	if match == nil {
+		return channelMeta{}, false
+	}
+	raw, ok := parseRawValue(rawValue)
+	if !ok {
+		return channelMeta{}, false
+	}
+	number, suffix := match[1], match[2]
+
+	scales := prtgUnitScales[suffix]
+	switch {
+	case suffix == "":
+		scales = []unitScale{{"", 1}}
+	case scales == nil:
+		scales = []unitScale{{"suffix: " + suffix, 1}}
+	}
+
+	var meta channelMeta
+	found := 0
+	for _, scale := range scales {
+		for _, displayed := range readDisplayedNumber(number) {
+			if !displayedFrom(displayed, raw, scale.Factor) {
+				continue
+			}
+			found++
+			meta.Unit, meta.Scale = scale.Unit, scale.Factor
+			if scale.Factor == 1 {
+				decimals := displayed.decimals
+				meta.Decimals = &decimals
+			}
+			break
+		}
+	}
+	if found != 1 {
+		return channelMeta{}, false
+	}
+	return meta, true
+}
+
+// readDisplayedNumber returns the possible readings of a number formatted by PRTG. The
+// decimal and thousands separators depend on the locale of the PRTG server, so "1.234"
+// may mean 1.234 or 1234.
+func readDisplayedNumber(number string) []displayedNumber {
+	i := strings.LastIndexAny(number, ".,")
+	if i < 0 {
+		value, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			return nil
+		}
+		return []displayedNumber{{value: value}}
+	}
+
+	sep, head, tail := number[i], number[:i], number[i+1:]
+	strip := strings.NewReplacer(".", "", ",", "")
+
+	var readings []displayedNumber
+	// The last separator is the decimal separator and all others group thousands.
+	if !strings.ContainsRune(head, rune(sep)) {
+		if value, err := strconv.ParseFloat(strip.Replace(head)+"."+tail, 64); err == nil {
+			readings = append(readings, displayedNumber{value: value, decimals: uint16(len(tail))})
+		}
+	}
+	// All separators group thousands.
+	if len(tail) == 3 && !strings.Contains(head, otherSeparator(sep)) {
+		if value, err := strconv.ParseFloat(strip.Replace(number), 64); err == nil {
+			readings = append(readings, displayedNumber{value: value})
+		}
+	}
+	return readings
+}
+
+// otherSeparator returns the separator type not equal to sep.
+func otherSeparator(sep byte) string {
+	if sep == '.' {
+		return ","
+	}
+	return "."
+}
+
+// displayedFrom reports whether PRTG displays raw scaled by factor as displayed, allowing
+// for the rounding to the displayed decimals.
+func displayedFrom(displayed displayedNumber, raw, factor float64) bool {
+	tolerance := 0.5*math.Pow(10, -float64(displayed.decimals)) + 1e-9*math.Abs(displayed.value)
+	return math.Abs(displayed.value-raw*factor) <= tolerance
+}
+
+// parseRawValue converts the lastvalue_raw column, a number or an empty string, to a float.
+func parseRawValue(raw interface{}) (float64, bool) {
+	switch v := raw.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// channelMetadata returns the display settings of the given historic data channels of a
+// sensor, keyed like channels. The channel table is cached per sensor, the limits per
+// channel; lookup failures are logged and yield no metadata.
+func (d *Datasource) channelMetadata(ctx context.Context, sensorID string, channels []string) map[string]channelMeta {
+	table, ok := d.channelMetaCache.Get(sensorID)
+	if !ok {
+		response, err := d.api.GetChannelMetadata(ctx, sensorID)
+		if err != nil {
+			backend.Logger.Warn("Channel metadata lookup failed", "objid", sensorID, "error", err)
+			return nil
+		}
+
+		table = make(map[string]channelMeta, len(response.Channels))
+		size := 0
+		for _, channel := range response.Channels {
+			if meta, ok := parseLastValue(channel.LastValue, channel.LastValueRAW); ok {
+				meta.ObjectId = channel.ObjectId
+				table[channel.Name] = meta
+				size += len(channel.Name) + len(meta.Unit) + 48
+			}
+		}
+		d.channelMetaCache.Set(sensorID, table, size)
+	}
+
+	metas := make(map[string]channelMeta, len(channels))
+	for _, channel := range channels {
+		meta, ok := lookupChannelMeta(table, channel)
+		if !ok {
+			continue
+		}
+		meta.Limits = d.channelLimits(ctx, sensorID, meta)
+		metas[channel] = meta
+	}
+	return metas
+}
+
+// channelLimitProperties are the channel properties holding the limits, in the order of
+// the channelLimits fields.
+var channelLimitProperties = []string{"limitminerror", "limitminwarning", "limitmaxwarning", "limitmaxerror"}
+
+// channelLimits reads the limits configured for a channel and converts them to raw values.
+// PRTG enters limits in the displayed unit, so channels of unknown scale get none.
+func (d *Datasource) channelLimits(ctx context.Context, sensorID string, meta channelMeta) *channelLimits {
+	if meta.Scale == 0 {
+		return nil
+	}
+	key := sensorID + "/" + strconv.FormatInt(meta.ObjectId, 10)
+	if limits, ok := d.channelLimitCache.Get(key); ok {
+		return limits
+	}
+
+	mode, err := d.api.GetChannelProperty(ctx, sensorID, meta.ObjectId, "limitmode")
+	if err != nil {
+		backend.Logger.Warn("Channel limit lookup failed", "objid", sensorID, "channel", meta.ObjectId, "error", err)
+		return nil
+	}
+
+	var limits *channelLimits
+	if strings.TrimSpace(mode) == "1" {
+		limits = &channelLimits{}
+		fields := []**float64{&limits.MinError, &limits.MinWarning, &limits.MaxWarning, &limits.MaxError}
+		for i, property := range channelLimitProperties {
+			value, err := d.api.GetChannelProperty(ctx, sensorID, meta.ObjectId, property)
+			if err != nil {
+				backend.Logger.Warn("Channel limit lookup failed", "objid", sensorID, "channel", meta.ObjectId, "error", err)
+				return nil
+			}
+			value = strings.Replace(strings.TrimSpace(value), ",", ".", 1)
+			if limit, err := strconv.ParseFloat(value, 64); err == nil {
+				raw := limit / meta.Scale
+				*fields[i] = &raw
+			}
+		}
+	}
+
+	d.channelLimitCache.Set(key, limits, len(key)+64)
+	return limits
+}
+
+// lookupChannelMeta finds the metadata of a historic data channel. Historic data uses
+// captions such as "Traffic In (speed)", while the channel table lists "Traffic In";
+// only speed captions share the unit of the channel's last value.
+func lookupChannelMeta(metas map[string]channelMeta, channel string) (channelMeta, bool) {
+	if meta, ok := metas[channel]; ok {
+		return meta, true
+	}
+	if base, ok := strings.CutSuffix(channel, " (speed)"); ok {
+		meta, ok := metas[base]
+		return meta, ok
+	}
+	return channelMeta{}, false
+}
+
+// applyChannelMeta sets unit, decimals and the thresholds of the channel limits on a value field.
+func applyChannelMeta(config *data.FieldConfig, meta channelMeta) {
+	config.Unit = meta.Unit
+	config.Decimals = meta.Decimals
+	config.Thresholds = limitThresholds(meta.Limits)
+}
+
+// limitThresholds maps channel limits to Grafana thresholds: values beyond an error limit
+// are red, beyond a warning limit orange and green otherwise. It returns nil without limits.
+func limitThresholds(limits *channelLimits) *data.ThresholdsConfig {
+	if limits == nil || (limits.MinError == nil && limits.MinWarning == nil && limits.MaxWarning == nil && limits.MaxError == nil) {
+		return nil
+	}
+
+	base := "green"
+	switch {
+	case limits.MinError != nil:
+		base = "red"
+	case limits.MinWarning != nil:
+		base = "orange"
+	}
+	steps := []data.Threshold{data.NewThreshold(math.Inf(-1), base, "")}
+	add := func(limit *float64, color string) {
+		if limit == nil {
+			return
+		}
+		// Steps must ascend; a limit below the previous one is inconsistent and skipped.
+		if *limit <= float64(steps[len(steps)-1].Value) {
+			return
+		}
+		steps = append(steps, data.NewThreshold(*limit, color, ""))
+	}
+	if limits.MinWarning != nil {
+		add(limits.MinError, "orange")
+	} else {
+		add(limits.MinError, "green")
+	}
+	add(limits.MinWarning, "green")
+	add(limits.MaxWarning, "orange")
+	add(limits.MaxError, "red")
+
+	return &data.ThresholdsConfig{Mode: data.ThresholdsModeAbsolute, Steps: steps}
+}
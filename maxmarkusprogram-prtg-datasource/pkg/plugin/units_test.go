@@ -0,0 +1,195 @@
+package plugin
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestReadDisplayedNumber(t *testing.T) {
+	tests := []struct {
+		number string
+		want   []displayedNumber
+	}{
+		{number: "12", want: []displayedNumber{{12, 0}}},
+		{number: "0.125", want: []displayedNumber{{0.125, 3}, {125, 0}}},
+		{number: "12.34", want: []displayedNumber{{12.34, 2}}},
+		{number: "1.234", want: []displayedNumber{{1.234, 3}, {1234, 0}}},
+		{number: "1,234", want: []displayedNumber{{1.234, 3}, {1234, 0}}},
+		{number: "1.234,5", want: []displayedNumber{{1234.5, 1}}},
+		{number: "1,234.56", want: []displayedNumber{{1234.56, 2}}},
+		{number: "1.234.567", want: []displayedNumber{{1234567, 0}}},
+		{number: "-3,5", want: []displayedNumber{{-3.5, 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.number, func(t *testing.T) {
+			if got := readDisplayedNumber(tt.number); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("readDisplayedNumber(%q) = %v, want %v", tt.number, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLastValue(t *testing.T) {
+	u16 := func(v uint16) *uint16 { return &v }
+
+	tests := []struct {
+		name      string
+		lastValue string
+		raw       interface{}
+		wantOk    bool
+		unit      string
+		scale     float64
+		decimals  *uint16
+	}{
+		{name: "same scale", lastValue: "12 msec", raw: 12.0, wantOk: true, unit: "ms", scale: 1, decimals: u16(0)},
+		{name: "fraction", lastValue: "0.125 msec", raw: 0.125, wantOk: true, unit: "ms", scale: 1, decimals: u16(3)},
+		{name: "decimal point", lastValue: "1.234 %", raw: 1.234, wantOk: true, unit: "percent", scale: 1, decimals: u16(3)},
+		{name: "thousands separator", lastValue: "1.234 %", raw: 1234.0, wantOk: true, unit: "percent", scale: 1, decimals: u16(0)},
+		{name: "bits from bytes", lastValue: "12 Mbit/s", raw: 1500000.0, wantOk: true, unit: "Bps", scale: 8e-6},
+		{name: "bits from bits", lastValue: "12 Mbit/s", raw: 12000000.0, wantOk: true, unit: "bps", scale: 1e-6},
+		{name: "binary bytes", lastValue: "1.234 MByte", raw: 1293942.0, wantOk: true, unit: "bytes", scale: 1.0 / (1 << 20)},
+		{name: "decimal bytes", lastValue: "1,234 MByte", raw: 1234000.0, wantOk: true, unit: "decbytes", scale: 1e-6},
+		{name: "idle traffic is ambiguous", lastValue: "0 Mbit/s", raw: 0.0, wantOk: false},
+		{name: "unknown scale", lastValue: "12 Mbit/s", raw: 42.0, wantOk: false},
+		{name: "unknown suffix", lastValue: "42 Sessions", raw: 42.0, wantOk: true, unit: "suffix: Sessions", scale: 1, decimals: u16(0)},
+		{name: "no suffix", lastValue: "7", raw: "7", wantOk: true, unit: "", scale: 1, decimals: u16(0)},
+		{name: "no raw value", lastValue: "7 %", raw: "", wantOk: false},
+		{name: "no data", lastValue: "No data", raw: 0.0, wantOk: false},
+		{name: "bound", lastValue: "< 1 %", raw: 0.5, wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta, ok := parseLastValue(tt.lastValue, tt.raw)
+			if ok != tt.wantOk {
+				t.Fatalf("parseLastValue(%q, %v) ok = %v, want %v", tt.lastValue, tt.raw, ok, tt.wantOk)
+			}
+			if !ok {
+				return
+			}
+			if meta.Unit != tt.unit || meta.Scale != tt.scale {
+				t.Errorf("unit, scale = %q, %g; want %q, %g", meta.Unit, meta.Scale, tt.unit, tt.scale)
+			}
+			switch {
+			case tt.decimals == nil && meta.Decimals != nil:
+				t.Errorf("decimals = %d, want unset", *meta.Decimals)
+			case tt.decimals != nil && (meta.Decimals == nil || *meta.Decimals != *tt.decimals):
+				t.Errorf("decimals = %v, want %d", meta.Decimals, *tt.decimals)
+			}
+		})
+	}
+}
+
+func TestLimitThresholds(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	type step struct {
+		value float64
+		color string
+	}
+
+	tests := []struct {
+		name   string
+		limits *channelLimits
+		want   []step
+	}{
+		{name: "no limits", limits: nil},
+		{name: "limits disabled", limits: &channelLimits{}},
+		{
+			name:   "upper limits",
+			limits: &channelLimits{MaxWarning: f(80), MaxError: f(90)},
+			want:   []step{{math.Inf(-1), "green"}, {80, "orange"}, {90, "red"}},
+		},
+		{
+			name:   "all limits",
+			limits: &channelLimits{MinError: f(1), MinWarning: f(5), MaxWarning: f(80), MaxError: f(90)},
+			want:   []step{{math.Inf(-1), "red"}, {1, "orange"}, {5, "green"}, {80, "orange"}, {90, "red"}},
+		},
+		{
+			name:   "lower error only",
+			limits: &channelLimits{MinError: f(10)},
+			want:   []step{{math.Inf(-1), "red"}, {10, "green"}},
+		},
+		{
+			name:   "lower warning only",
+			limits: &channelLimits{MinWarning: f(10)},
+			want:   []step{{math.Inf(-1), "orange"}, {10, "green"}},
+		},
+		{
+			name:   "inconsistent limit skipped",
+			limits: &channelLimits{MaxWarning: f(90), MaxError: f(80)},
+			want:   []step{{math.Inf(-1), "green"}, {90, "orange"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := limitThresholds(tt.limits)
+			if tt.want == nil {
+				if config != nil {
+					t.Fatalf("limitThresholds() = %v, want nil", config)
+				}
+				return
+			}
+			if config == nil || config.Mode != data.ThresholdsModeAbsolute {
+				t.Fatalf("limitThresholds() = %v, want absolute thresholds", config)
+			}
+			var got []step
+			for _, s := range config.Steps {
+				got = append(got, step{float64(s.Value), s.Color})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("steps = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChannelLimits(t *testing.T) {
+	properties := map[string]string{
+		"limitmode":       "1",
+		"limitmaxwarning": "80",
+		"limitmaxerror":   "95,5",
+		"limitminwarning": "",
+		"limitminerror":   "(Property not found)",
+	}
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		q := r.URL.Query()
+		if r.URL.Path != "/api/getobjectproperty.htm" || q.Get("subtype") != "channel" || q.Get("subid") != "2" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><prtg><version>24.1</version><result>` + properties[q.Get("name")] + `</result></prtg>`))
+	}))
+	defer srv.Close()
+
+	d := &Datasource{
+		api:               NewApi(srv.URL, Credentials{}, srv.Client(), 0),
+		channelLimitCache: newTTLCache[*channelLimits](channelMetadataTTL, defaultCacheMaxEntries, defaultCacheMaxBytes),
+	}
+	meta := channelMeta{ObjectId: 2, Unit: "Bps", Scale: 8e-6}
+
+	limits := d.channelLimits(context.Background(), "1001", meta)
+	if limits == nil || limits.MinError != nil || limits.MinWarning != nil {
+		t.Fatalf("channelLimits() = %+v, want only upper limits", limits)
+	}
+	// Limits are entered in Mbit/s and converted to the raw bytes per second.
+	if *limits.MaxWarning != 80/8e-6 || *limits.MaxError != 95.5/8e-6 {
+		t.Fatalf("upper limits = %g, %g; want %g, %g", *limits.MaxWarning, *limits.MaxError, 80/8e-6, 95.5/8e-6)
+	}
+
+	before := requests
+	d.channelLimits(context.Background(), "1001", meta)
+	if requests != before {
+		t.Fatalf("cached limits sent %d more requests", requests-before)
+	}
+
+	if got := d.channelLimits(context.Background(), "1001", channelMeta{ObjectId: 2}); got != nil {
+		t.Fatalf("channelLimits() without scale = %+v, want nil", got)
+	}
+}
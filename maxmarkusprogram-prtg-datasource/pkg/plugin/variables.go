@@ -0,0 +1,123 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// variableOption is a single entry of a template variable.
+type variableOption struct {
+	Text  string
+	Value string
+}
+
+// handleVariableQuery returns name/objid pairs for template variables. VariableType selects
+// groups, devices, sensors or channels; Parent restricts the result to the children of a
+// group, device or sensor given by objid or name, so variables can be chained:
+// $group -> $device -> $sensor -> $channel.
+func (d *Datasource) handleVariableQuery(ctx context.Context, qm queryModel) backend.DataResponse {
+	var response backend.DataResponse
+	var options []variableOption
+
+	switch qm.VariableType {
+	case "groups":
+		groups, err := d.api.GetGroups(ctx, parentFilter(qm.Parent, ""))
+		if err != nil {
+			return apiErrorResponse("API request failed", err)
+		}
+		parents := groupParents(qm.Parent, groups.Groups)
+		for _, g := range groups.Groups {
+			if parents == nil || parents[g.ParentId] {
+				options = append(options, variableOption{Text: g.Group, Value: strconv.FormatInt(g.ObjectId, 10)})
+			}
+		}
+
+	case "devices":
+		devices, err := d.api.GetDevices(ctx, parentFilter(qm.Parent, "group"))
+		if err != nil {
+			return apiErrorResponse("API request failed", err)
+		}
+		for _, dev := range devices.Devices {
+			if matchParent(qm.Parent, dev.ParentId, dev.Group) {
+				options = append(options, variableOption{Text: dev.Device, Value: strconv.FormatInt(dev.ObjectId, 10)})
+			}
+		}
+
+	case "sensors":
+		sensors, err := d.api.GetSensors(ctx, parentFilter(qm.Parent, "device"))
+		if err != nil {
+			return apiErrorResponse("API request failed", err)
+		}
+		for _, s := range sensors.Sensors {
+			if matchParent(qm.Parent, s.ParentId, s.Device) {
+				options = append(options, variableOption{Text: s.Sensor, Value: strconv.FormatInt(s.ObjectId, 10)})
+			}
+		}
+
+	case "channels":
+		if qm.Parent == "" {
+			return backend.ErrDataResponse(backend.StatusBadRequest, "channel variables require a parent sensor objid")
+		}
+		channels, err := d.api.GetChannelMetadata(ctx, qm.Parent)
+		if err != nil {
+			return apiErrorResponse("API request failed", err)
+		}
+		// Metrics queries address channels by name, so name is used as text and value.
+		for _, c := range channels.Channels {
+			options = append(options, variableOption{Text: c.Name, Value: c.Name})
+		}
+
+	default:
+		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("Unknown variable type: %s", qm.VariableType))
+	}
+
+	texts := make([]string, len(options))
+	values := make([]string, len(options))
+	for i, option := range options {
+		texts[i] = option.Text
+		values[i] = option.Value
+	}
+
+	response.Frames = append(response.Frames, data.NewFrame("variable",
+		data.NewField("text", nil, texts),
+		data.NewField("value", nil, values),
+	))
+	return response
+}
+
+// matchParent reports whether an object belongs to parent, which may be an objid or a name.
+// An empty parent matches every object.
+func matchParent(parent string, parentId int64, parentName string) bool {
+	if parent == "" {
+		return true
+	}
+	if id, err := strconv.ParseInt(parent, 10, 64); err == nil {
+		return id == parentId
+	}
+	return parentName != "" && parent == parentName
+}
+
+// groupParents returns the objids a group's parentid must match for parent, or nil if
+// parent is empty. The group table has no parent name column, so a parent given by name
+// is resolved against the names of the groups themselves; the unfiltered group list
+// contains every candidate parent.
+func groupParents(parent string, groups []PrtgGroupListItemStruct) map[int64]bool {
+	if parent == "" {
+		return nil
+	}
+	parents := make(map[int64]bool)
+	if id, err := strconv.ParseInt(parent, 10, 64); err == nil {
+		parents[id] = true
+		return parents
+	}
+	for _, g := range groups {
+		if g.Group == parent {
+			parents[g.ObjectId] = true
+		}
+	}
+	return parents
+}
@@ -0,0 +1,35 @@
+package plugin
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroupParents(t *testing.T) {
+	groups := []PrtgGroupListItemStruct{
+		{Group: "Root", ObjectId: 0},
+		{Group: "Berlin", ObjectId: 10, ParentId: 0},
+		{Group: "Servers", ObjectId: 11, ParentId: 10},
+		{Group: "Hamburg", ObjectId: 20, ParentId: 0},
+		{Group: "Servers", ObjectId: 21, ParentId: 20},
+	}
+
+	tests := []struct {
+		name   string
+		parent string
+		want   map[int64]bool
+	}{
+		{name: "no parent", parent: "", want: nil},
+		{name: "objid", parent: "10", want: map[int64]bool{10: true}},
+		{name: "unique name", parent: "Berlin", want: map[int64]bool{10: true}},
+		{name: "ambiguous name", parent: "Servers", want: map[int64]bool{11: true, 21: true}},
+		{name: "unknown name", parent: "Munich", want: map[int64]bool{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := groupParents(tt.parent, groups); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("groupParents(%q) = %v, want %v", tt.parent, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,8 +1,17 @@
 import React, { useEffect, useState } from 'react'
-import { InlineField, Select, Stack, FieldSet, InlineSwitch } from '@grafana/ui'
+import { InlineField, Input, Select, MultiSelect, Stack, FieldSet, InlineSwitch } from '@grafana/ui'
 import { QueryEditorProps, SelectableValue } from '@grafana/data'
 import { DataSource } from '../datasource'
-import { MyDataSourceOptions, MyQuery, queryTypeOptions, QueryType, propertyList, filterPropertyList } from '../types'
+import {
+  MyDataSourceOptions,
+  MyQuery,
+  queryTypeOptions,
+  QueryType,
+  propertyList,
+  filterPropertyList,
+  variableTypeOptions,
+  averagingOptions,
+} from '../types'
 
 type Props = QueryEditorProps<DataSource, MyQuery, MyDataSourceOptions>
 
@@ -10,6 +19,7 @@ export function QueryEditor({ query, onChange, onRunQuery, datasource }: Props)
   const isMetricsMode = query.queryType === QueryType.Metrics
   const isRawMode = query.queryType === QueryType.Raw
   const isTextMode = query.queryType === QueryType.Text
+  const isVariableMode = query.queryType === QueryType.Variable
 
   const [group, setGroup] = useState<string>('')
   const [device, setDevice] = useState<string>('')
@@ -39,8 +49,8 @@ export function QueryEditor({ query, onChange, onRunQuery, datasource }: Props)
         const response = await datasource.getGroups()
         if (response && Array.isArray(response.groups)) {
           const groupOptions = response.groups.map((group) => ({
-            label: group.group,
-            value: group.group.toString(),
+            label: group.name,
+            value: group.name,
           }))
           setLists((prev) => ({
             ...prev,
@@ -62,13 +72,11 @@ export function QueryEditor({ query, onChange, onRunQuery, datasource }: Props)
     async function fetchDevices() {
       setIsLoading(true)
       try {
-        const response = await datasource.getDevices()
+        const response = await datasource.getDevices(group ? { group } : {})
         if (response && Array.isArray(response.devices)) {
-          const filteredDevices = group ? response.devices.filter((device) => device.group === group) : response.devices
-
-          const deviceOptions = filteredDevices.map((device) => ({
-            label: device.device,
-            value: device.device.toString(),
+          const deviceOptions = response.devices.map((device) => ({
+            label: device.name,
+            value: device.name,
           }))
           setLists((prev) => ({
             ...prev,
@@ -90,14 +98,11 @@ export function QueryEditor({ query, onChange, onRunQuery, datasource }: Props)
     async function fetchSensors() {
       setIsLoading(true)
       try {
-        const response = await datasource.getSensors()
+        const response = await datasource.getSensors(device ? { device } : {})
         if (response && Array.isArray(response.sensors)) {
-          const filteredSensors = device
-            ? response.sensors.filter((sensor) => sensor.device === device)
-            : response.sensors
-          const sensorOptions = filteredSensors.map((sensor) => ({
-            label: sensor.sensor,
-            value: sensor.sensor.toString(),
+          const sensorOptions = response.sensors.map((sensor) => ({
+            label: sensor.name,
+            value: sensor.name,
           }))
           setLists((prev) => ({
             ...prev,
@@ -251,9 +256,9 @@ export function QueryEditor({ query, onChange, onRunQuery, datasource }: Props)
 
   const findSensorObjid = async (sensorName: string) => {
     try {
-      const response = await datasource.getSensors()
+      const response = await datasource.getSensors(device ? { device, search: sensorName } : { search: sensorName })
       if (response && Array.isArray(response.sensors)) {
-        const sensor = response.sensors.find((s) => s.sensor === sensorName)
+        const sensor = response.sensors.find((s) => s.name === sensorName)
         if (sensor) {
           setObjid(sensor.objid.toString())
           return sensor.objid.toString()
@@ -295,6 +300,38 @@ export function QueryEditor({ query, onChange, onRunQuery, datasource }: Props)
     onRunQuery()
   }
 
+  const onChannelsChange = (values: Array<SelectableValue<string>>) => {
+    onChange({ ...query, channels: values.map((v) => v.value!) })
+    onRunQuery()
+  }
+
+  const onObjectListChange = (key: 'groups' | 'devices' | 'sensors') => (values: Array<SelectableValue<string>>) => {
+    onChange({ ...query, [key]: values.map((v) => v.value!) })
+    onRunQuery()
+  }
+
+  const onSelectorChange = (e: React.ChangeEvent<HTMLInputElement>) => {
+    onChange({ ...query, selector: e.currentTarget.value })
+  }
+
+  const onTagFilterChange = (e: React.ChangeEvent<HTMLInputElement>) => {
+    onChange({ ...query, tagFilter: e.currentTarget.value })
+  }
+
+  const onVariableTypeChange = (value: SelectableValue<string>) => {
+    onChange({ ...query, variableType: value.value! })
+    onRunQuery()
+  }
+
+  const onParentChange = (e: React.ChangeEvent<HTMLInputElement>) => {
+    onChange({ ...query, parent: e.currentTarget.value })
+  }
+
+  const onAveragingChange = (value: SelectableValue<string>) => {
+    onChange({ ...query, averaging: value.value! })
+    onRunQuery()
+  }
+
   const onPropertyChange = (value: SelectableValue<string>) => {
     onChange({ ...query, property: value.value! })
     onRunQuery()
@@ -383,15 +420,33 @@ export function QueryEditor({ query, onChange, onRunQuery, datasource }: Props)
           {isMetricsMode && (
             <InlineField label="Channel" labelWidth={20} grow>
               <Select
-                isLoading={!lists.channels.length}
+                isLoading={!!objid && !lists.channels.length}
                 options={lists.channels}
                 value={query.channel}
                 onChange={onChannelChange}
                 width={47}
                 allowCustomValue
-                placeholder="Select Channel or type '*'"
+                placeholder="Select Channel or type a name, '*' or variable"
+                isClearable
+              />
+            </InlineField>
+          )}
+
+          {isMetricsMode && (
+            <InlineField
+              label="Channels"
+              labelWidth={20}
+              grow
+              tooltip="Several channels, '*' for all. Without a single sensor, type the channel names or variables to query on every selected sensor"
+            >
+              <MultiSelect
+                options={[{ label: 'All channels', value: '*' }, ...lists.channels]}
+                value={query.channels || []}
+                onChange={onChannelsChange}
+                width={47}
+                allowCustomValue
+                placeholder="Select Channels"
                 isClearable
-                isDisabled={!query.sensor}
               />
             </InlineField>
           )}
@@ -400,6 +455,90 @@ export function QueryEditor({ query, onChange, onRunQuery, datasource }: Props)
 
       {/* Zweite Zeile: Options */}
       <Stack direction="column" gap={1}>
+        {isMetricsMode && (
+          <InlineField
+            label="Groups"
+            labelWidth={20}
+            tooltip="Query sensors of several groups; names, wildcards (*, ?) or /regex/"
+          >
+            <MultiSelect
+              options={lists.groups}
+              value={query.groups || []}
+              onChange={onObjectListChange('groups')}
+              width={47}
+              allowCustomValue
+              placeholder="Select Groups"
+              isClearable
+            />
+          </InlineField>
+        )}
+
+        {isMetricsMode && (
+          <InlineField
+            label="Devices"
+            labelWidth={20}
+            tooltip="Query sensors of several devices; names, wildcards (*, ?) or /regex/"
+          >
+            <MultiSelect
+              options={lists.devices}
+              value={query.devices || []}
+              onChange={onObjectListChange('devices')}
+              width={47}
+              allowCustomValue
+              placeholder="Select Devices"
+              isClearable
+            />
+          </InlineField>
+        )}
+
+        {isMetricsMode && (
+          <InlineField
+            label="Sensors"
+            labelWidth={20}
+            tooltip="Query several sensors; names, objids, wildcards (*, ?) or /regex/"
+          >
+            <MultiSelect
+              options={lists.sensors}
+              value={query.sensors || []}
+              onChange={onObjectListChange('sensors')}
+              width={47}
+              allowCustomValue
+              placeholder="Select Sensors"
+              isClearable
+            />
+          </InlineField>
+        )}
+
+        {isMetricsMode && (
+          <InlineField
+            label="Selector"
+            labelWidth={20}
+            tooltip="Select sensors by pattern, e.g. group=/^DC-.*/ device=*-fw* sensor=Ping*"
+          >
+            <Input
+              value={query.selector || ''}
+              onChange={onSelectorChange}
+              onBlur={onRunQuery}
+              width={47}
+              placeholder="group=/^DC-.*/ device=*-fw* sensor=Ping*"
+            />
+          </InlineField>
+        )}
+
+        <InlineField
+          label="Tags"
+          labelWidth={20}
+          tooltip="Select objects by tag with AND, OR, NOT and parentheses, e.g. core-switch AND snmptraffic"
+        >
+          <Input
+            value={query.tagFilter || ''}
+            onChange={onTagFilterChange}
+            onBlur={onRunQuery}
+            width={47}
+            placeholder="core-switch AND NOT lab"
+          />
+        </InlineField>
+
         {isMetricsMode && (
           <FieldSet label="Options">
             <Stack direction="row" gap={1}>
@@ -414,6 +553,33 @@ export function QueryEditor({ query, onChange, onRunQuery, datasource }: Props)
               <InlineField label="Include Sensor" labelWidth={15}>
                 <InlineSwitch value={query.includeSensorName || false} onChange={onIncludeSensorName} />
               </InlineField>
+
+              <InlineField label="Averaging" labelWidth={12} tooltip="PRTG averaging interval; Auto follows the panel resolution">
+                <Select
+                  options={averagingOptions}
+                  value={query.averaging || 'auto'}
+                  onChange={onAveragingChange}
+                  width={20}
+                />
+              </InlineField>
+            </Stack>
+          </FieldSet>
+        )}
+
+        {isVariableMode && (
+          <FieldSet label="Variable">
+            <Stack direction="row" gap={1}>
+              <InlineField label="Type" labelWidth={16}>
+                <Select
+                  options={variableTypeOptions}
+                  value={query.variableType}
+                  onChange={onVariableTypeChange}
+                  width={32}
+                />
+              </InlineField>
+              <InlineField label="Parent" labelWidth={16} tooltip="Objid or name of the parent, e.g. $group">
+                <Input value={query.parent || ''} onChange={onParentChange} onBlur={onRunQuery} width={32} />
+              </InlineField>
             </Stack>
           </FieldSet>
         )}
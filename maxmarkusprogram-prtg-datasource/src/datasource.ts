@@ -1,4 +1,10 @@
-import { AnnotationQuery, AnnotationSupport, DataSourceInstanceSettings, ScopedVars } from '@grafana/data'
+import {
+  AnnotationQuery,
+  AnnotationSupport,
+  DataSourceInstanceSettings,
+  DataSourceVariableSupport,
+  ScopedVars,
+} from '@grafana/data'
 import { DataSourceWithBackend, getTemplateSrv } from '@grafana/runtime'
 import {
   MyQuery,
@@ -7,35 +13,57 @@ import {
   PRTGDeviceListResponse,
   PRTGSensorListResponse,
   PRTGChannelListResponse,
+  PRTGResourceQuery,
+  QueryType,
 } from './types'
 
 export class DataSource extends DataSourceWithBackend<MyQuery, MyDataSourceOptions> {
   constructor(instanceSettings: DataSourceInstanceSettings<MyDataSourceOptions>) {
     super(instanceSettings)
+    this.variables = new VariableSupport()
+    // Annotations use the backend "annotations" query type with the regular query editor.
+    this.annotations = {}
   }
 
   applyTemplateVariables(query: MyQuery, scopedVars: ScopedVars) {
     return {
       ...query,
+      objid: typeof query.objid === 'string' ? getTemplateSrv().replace(query.objid, scopedVars) : query.objid,
+      group: getTemplateSrv().replace(query.group, scopedVars),
+      device: getTemplateSrv().replace(query.device, scopedVars),
+      sensor: getTemplateSrv().replace(query.sensor, scopedVars),
       channel: getTemplateSrv().replace(query.channel, scopedVars),
+      groups: interpolateList(query.groups, scopedVars),
+      devices: interpolateList(query.devices, scopedVars),
+      sensors: interpolateList(query.sensors, scopedVars),
+      channels: interpolateList(query.channels, scopedVars),
+      selector: getTemplateSrv().replace(query.selector, scopedVars),
+      tagFilter: getTemplateSrv().replace(query.tagFilter, scopedVars),
+      parent: getTemplateSrv().replace(query.parent, scopedVars),
     }
   }
 
   filterQuery(query: MyQuery): boolean {
     // if no query has been provided, prevent the query from being executed
-    return !!query.channel
+    if (query.queryType === QueryType.Variable) {
+      return !!query.variableType
+    }
+    if (query.queryType === QueryType.Annotations) {
+      return true
+    }
+    return !!query.channel || (query.channels || []).length > 0
   }
 
-  async getGroups(): Promise<PRTGGroupListResponse> {
-    return this.getResource('groups')
+  async getGroups(params: PRTGResourceQuery = {}): Promise<PRTGGroupListResponse> {
+    return this.getResource('groups', params)
   }
 
-  async getDevices(): Promise<PRTGDeviceListResponse> {
-    return this.getResource('devices')
+  async getDevices(params: PRTGResourceQuery = {}): Promise<PRTGDeviceListResponse> {
+    return this.getResource('devices', params)
   }
 
-  async getSensors(): Promise<PRTGSensorListResponse> {
-    return this.getResource('sensors')
+  async getSensors(params: PRTGResourceQuery = {}): Promise<PRTGSensorListResponse> {
+    return this.getResource('sensors', params)
   }
 
   async getChannels(objid: string): Promise<PRTGChannelListResponse> {
@@ -46,3 +74,22 @@ export class DataSource extends DataSourceWithBackend<MyQuery, MyDataSourceOptio
   }
   annotations?: AnnotationSupport<MyQuery, AnnotationQuery<MyQuery>> | undefined
 }
+
+// Separates the values of multi-value variables while interpolating list entries.
+const LIST_SEPARATOR = '\u001f'
+
+// Interpolates every entry of a list; an entry holding a multi-value variable expands
+// into one entry per selected value.
+function interpolateList(values: string[] | undefined, scopedVars: ScopedVars): string[] {
+  const result: string[] = []
+  for (const value of values || []) {
+    const replaced = getTemplateSrv().replace(value, scopedVars, (v: string | string[]) =>
+      Array.isArray(v) ? v.join(LIST_SEPARATOR) : v
+    )
+    result.push(...replaced.split(LIST_SEPARATOR).filter((v) => v !== ''))
+  }
+  return result
+}
+
+// Template variables use the backend "variable" query type through the regular query editor.
+export class VariableSupport extends DataSourceVariableSupport<DataSource> {}
@@ -4,8 +4,30 @@ import { DataQuery } from '@grafana/schema';
 export enum QueryType {
   Metrics = 'metrics',
   Raw = 'raw',
-  Text = 'text'
-}
+  Text = 'text',
+  Variable = 'variable',
+  Annotations = 'annotations'
+}
+
+export const variableTypeOptions = [
+  { label: 'Groups', value: 'groups' },
+  { label: 'Devices', value: 'devices' },
+  { label: 'Sensors', value: 'sensors' },
+  { label: 'Channels', value: 'channels' },
+];
+
+export const averagingOptions = [
+  { label: 'Auto', value: 'auto', description: 'Follow the panel resolution' },
+  { label: 'Raw', value: 'raw', description: 'No averaging' },
+  { label: '1 minute', value: '60' },
+  { label: '5 minutes', value: '300' },
+  { label: '15 minutes', value: '900' },
+  { label: '30 minutes', value: '1800' },
+  { label: '1 hour', value: '3600' },
+  { label: '2 hours', value: '7200' },
+  { label: '4 hours', value: '14400' },
+  { label: '1 day', value: '86400' },
+];
 
 export interface MyQuery extends DataQuery {
   group: string;
@@ -23,6 +45,11 @@ export interface MyQuery extends DataQuery {
   devices: Array<string>;
   sensors: Array<string>;
   channels: Array<string>;
+  selector?: string;
+  tagFilter?: string;
+  variableType?: string;
+  parent?: string;
+  averaging?: string;
 }
 
 export interface DataPoint {
@@ -34,16 +61,46 @@ export interface DataSourceResponse {
   datapoints: DataPoint[];
 }
 
+export enum AuthMode {
+  ApiToken = 'apitoken',
+  Passhash = 'passhash',
+  Password = 'password',
+}
+
+export const authModeOptions = [
+  { label: 'API Token', value: AuthMode.ApiToken },
+  { label: 'Username + Passhash', value: AuthMode.Passhash },
+  { label: 'Username + Password', value: AuthMode.Password },
+];
+
 /**
  * These are options configured for each DataSource instance
  */
 export interface MyDataSourceOptions extends DataSourceJsonData {
   path?: string;
   cacheTime?: number;
+  authMode?: AuthMode;
+  username?: string;
+  maxConcurrentQueries?: number;
+  retryMaxAttempts?: number;
+  retryBaseDelay?: number;
+  retryMaxDelay?: number;
+  requestsPerSecond?: number;
+  requestBurst?: number;
+  maxInFlightRequests?: number;
+  tlsSkipVerify?: boolean;
+  tlsAuth?: boolean;
+  tlsAuthWithCACert?: boolean;
+  serverName?: string;
 }
 
 export interface MySecureJsonData {
   apiKey?: string;
+  passhash?: string;
+  password?: string;
+  tlsCACert?: string;
+  tlsClientCert?: string;
+  tlsClientKey?: string;
 }
 
 
@@ -90,10 +147,32 @@ export interface PRTGItem {
   
 }
 
+/**
+ * Slim group, device or sensor as returned by the groups, devices and sensors resources
+ */
+export interface PRTGObject {
+  name: string;
+  objid: number;
+  parent?: string;
+  parentid: number;
+  status: string;
+}
+
+/**
+ * Filter and paging parameters of the groups, devices and sensors resources
+ */
+export interface PRTGResourceQuery {
+  group?: string;
+  device?: string;
+  status?: string;
+  search?: string;
+  limit?: number;
+  offset?: number;
+}
+
 export interface PRTGGroupListResponse {
-  prtgversion: string;
-  treesize: number;
-  groups: PRTGItem[];
+  total: number;
+  groups: PRTGObject[];
 }
 
 export interface PRTGGroupResponse {
@@ -101,9 +180,8 @@ export interface PRTGGroupResponse {
 }
 
 export interface PRTGDeviceListResponse {
-  prtgversion: string;
-  treesize: number;
-  devices: PRTGItem[];
+  total: number;
+  devices: PRTGObject[];
 }
 
 export interface PRTGDeviceResponse {
@@ -111,9 +189,8 @@ export interface PRTGDeviceResponse {
 }
 
 export interface PRTGSensorListResponse {
-  prtgversion: string;
-  treesize: number;
-  sensors: PRTGItem[];
+  total: number;
+  sensors: PRTGObject[];
 }
 
 export interface PRTGSensorResponse {